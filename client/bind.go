@@ -0,0 +1,139 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Тег значения поля по умолчанию
+const DEFAULT_TAG = "default"
+
+// ErrInvalidConfig проверяется с помощью errors.Is для конфигураций,
+// отклоненных функцией Validate
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Validator interface
+//
+// Проверка конфигурации после декодирования. Конфигурация, для которой
+// Validate возвращает ошибку, не передается функциям обновления.
+type Validator interface {
+	Validate() error
+}
+
+// InvalidConfigError struct
+type InvalidConfigError struct {
+	Service string
+	Err     error
+}
+
+// Error function
+func (e *InvalidConfigError) Error() string {
+	if e.Service == EMPTY_STRING {
+		return fmt.Sprintf("config is invalid: %v", e.Err)
+	}
+
+	return fmt.Sprintf("config of service %q is invalid: %v", e.Service, e.Err)
+}
+
+// Unwrap function
+func (e *InvalidConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Is function
+func (e *InvalidConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// Bind function
+//
+// Декодирует конфигурацию в значение типа T. Для структур сначала
+// устанавливаются значения по умолчанию из тегов default, затем
+// декодируется JSON, после чего значения полей с тегом env заменяются
+// значениями переменных окружения (как в cleanenv). В конце вызывается
+// функция Validate, если тип T ее реализует.
+func Bind[T any](data []byte) (T, error) {
+	var value T
+
+	rv := reflect.ValueOf(&value).Elem()
+	isStruct := rv.Kind() == reflect.Struct
+
+	if isStruct {
+		if err := setDefaults(rv); err != nil {
+			return value, &DecodeError{Err: err}
+		}
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, &DecodeError{Err: err}
+	}
+
+	if isStruct {
+		if err := cleanenv.ReadEnv(&value); err != nil {
+			return value, &DecodeError{Err: err}
+		}
+	}
+
+	if v, ok := interface{}(&value).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return value, &InvalidConfigError{Err: err}
+		}
+	}
+
+	return value, nil
+}
+
+// setDefaults function
+//
+// Значения по умолчанию задаются в формате JSON, кроме строк
+// и time.Duration, например default:"10" или default:"5s".
+func setDefaults(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		if def, ok := field.Tag.Lookup(DEFAULT_TAG); ok {
+			if err := setDefault(fv, def); err != nil {
+				return fmt.Errorf("invalid default value of field %s: %w", field.Name, err)
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct {
+			if err := setDefaults(fv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setDefault function
+func setDefault(fv reflect.Value, def string) error {
+	switch {
+	case fv.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case fv.Kind() == reflect.String:
+		fv.SetString(def)
+		return nil
+	default:
+		return json.Unmarshal([]byte(def), fv.Addr().Interface())
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type bindDB struct {
+	Host     string        `json:"host" default:"localhost"`
+	PoolSize int           `json:"pool_size" default:"10" env:"TEST_BIND_POOL_SIZE"`
+	Timeout  time.Duration `json:"timeout" default:"5s"`
+}
+
+type bindConfig struct {
+	Name  string   `json:"name" env:"TEST_BIND_NAME"`
+	Tags  []string `json:"tags" default:"[\"a\",\"b\"]"`
+	DB    bindDB   `json:"db"`
+	Debug bool     `json:"debug" default:"true"`
+}
+
+// Validate function
+func (c *bindConfig) Validate() error {
+	if c.DB.PoolSize <= 0 {
+		return errors.New("db.pool_size must be positive")
+	}
+
+	return nil
+}
+
+func TestBindDefaults(t *testing.T) {
+	cfg, err := Bind[bindConfig]([]byte(`{"name":"svc","db":{"host":"db1"},"debug":false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Name != "svc" || cfg.DB.Host != "db1" || cfg.Debug {
+		t.Fatalf("JSON values are not applied: %+v", cfg)
+	}
+	if cfg.DB.PoolSize != 10 || cfg.DB.Timeout != 5*time.Second || len(cfg.Tags) != 2 {
+		t.Fatalf("defaults are not applied: %+v", cfg)
+	}
+}
+
+func TestBindEnv(t *testing.T) {
+	t.Setenv("TEST_BIND_NAME", "from-env")
+	t.Setenv("TEST_BIND_POOL_SIZE", "42")
+
+	cfg, err := Bind[bindConfig]([]byte(`{"name":"svc","db":{"pool_size":5}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Name != "from-env" || cfg.DB.PoolSize != 42 {
+		t.Fatalf("env values are not applied: %+v", cfg)
+	}
+}
+
+func TestBindValidate(t *testing.T) {
+	_, err := Bind[bindConfig]([]byte(`{"db":{"pool_size":-1}}`))
+
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestBindDecodeError(t *testing.T) {
+	_, err := Bind[bindConfig]([]byte(`{"db":{"pool_size":"many"}}`))
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want *DecodeError", err)
+	}
+}
+
+func TestBindNonStruct(t *testing.T) {
+	values, err := Bind[map[string]int]([]byte(`{"a":1}`))
+	if err != nil || values["a"] != 1 {
+		t.Fatalf("Bind map = %v, %v", values, err)
+	}
+}
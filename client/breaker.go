@@ -0,0 +1,177 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается без запроса к серверу, пока выключатель разомкнут
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState type
+type BreakerState int
+
+// Состояния автоматического выключателя
+const (
+	BREAKER_CLOSED BreakerState = iota
+	BREAKER_OPEN
+	BREAKER_HALF_OPEN
+)
+
+// String function
+func (s BreakerState) String() string {
+	switch s {
+	case BREAKER_CLOSED:
+		return "closed"
+	case BREAKER_OPEN:
+		return "open"
+	case BREAKER_HALF_OPEN:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerSettings struct
+type BreakerSettings struct {
+	// Число ошибок подряд, после которого выключатель размыкается,
+	// 0 отключает выключатель
+	FailureThreshold int
+
+	// Время до пробного запроса после размыкания выключателя
+	OpenTimeout time.Duration
+}
+
+// DefaultBreakerSettings function
+func DefaultBreakerSettings() BreakerSettings {
+	return BreakerSettings{
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+	}
+}
+
+// breaker struct
+//
+// Автоматический выключатель: после FailureThreshold ошибок подряд
+// запросы к серверу не выполняются в течение OpenTimeout, затем
+// выполняется один пробный запрос, по результату которого выключатель
+// замыкается или снова размыкается.
+type breaker struct {
+	settings BreakerSettings
+	onChange func(from BreakerState, to BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newBreaker function
+func newBreaker(settings BreakerSettings, onChange func(BreakerState, BreakerState)) *breaker {
+	return &breaker{
+		settings: settings,
+		onChange: onChange,
+	}
+}
+
+// allow function
+//
+// Проверяет, можно ли выполнить запрос к серверу.
+func (b *breaker) allow() error {
+	if b.settings.FailureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	from := b.state
+
+	var err error
+
+	switch b.state {
+	case BREAKER_OPEN:
+		if time.Since(b.openedAt) < b.settings.OpenTimeout {
+			err = ErrCircuitOpen
+			break
+		}
+		b.state = BREAKER_HALF_OPEN
+		b.probing = true
+	case BREAKER_HALF_OPEN:
+		// Пока выполняется пробный запрос, остальные отклоняются
+		if b.probing {
+			err = ErrCircuitOpen
+			break
+		}
+		b.probing = true
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return err
+}
+
+// done function
+//
+// Учитывает результат запроса, разрешенного allow.
+func (b *breaker) done(failed bool) {
+	if b.settings.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	from := b.state
+
+	b.probing = false
+
+	if failed {
+		b.failures++
+		if b.state == BREAKER_HALF_OPEN || b.failures >= b.settings.FailureThreshold {
+			b.openedAt = time.Now()
+			b.state = BREAKER_OPEN
+		}
+	} else {
+		b.failures = 0
+		b.state = BREAKER_CLOSED
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// cancel function
+//
+// Запрос, разрешенный allow, отменен вызывающей стороной: результат
+// не учитывается, а в полуоткрытом состоянии следующий запрос
+// снова становится пробным.
+func (b *breaker) cancel() {
+	if b.settings.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// current function
+func (b *breaker) current() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// notify function
+//
+// Обработчик вызывается без блокировки, поэтому может обращаться к клиенту.
+func (b *breaker) notify(from BreakerState, to BreakerState) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
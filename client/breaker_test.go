@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	var changes []BreakerState
+	b := newBreaker(BreakerSettings{FailureThreshold: 2, OpenTimeout: 20 * time.Millisecond},
+		func(from BreakerState, to BreakerState) { changes = append(changes, to) })
+
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("request %d is rejected: %v", i, err)
+		}
+		b.done(true)
+	}
+
+	if b.current() != BREAKER_OPEN {
+		t.Fatalf("state = %s after threshold failures, want open", b.current())
+	}
+	if err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("open breaker allows request: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	// Пробный запрос только один
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe is rejected: %v", err)
+	}
+	if err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("second request during probe is allowed: %v", err)
+	}
+
+	// Неудачный пробный запрос снова размыкает выключатель
+	b.done(true)
+	if b.current() != BREAKER_OPEN {
+		t.Fatalf("state = %s after failed probe, want open", b.current())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe is rejected: %v", err)
+	}
+	b.done(false)
+
+	if b.current() != BREAKER_CLOSED {
+		t.Fatalf("state = %s after successful probe, want closed", b.current())
+	}
+
+	want := []BreakerState{BREAKER_OPEN, BREAKER_HALF_OPEN, BREAKER_OPEN, BREAKER_HALF_OPEN, BREAKER_CLOSED}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("state changes = %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := newBreaker(BreakerSettings{FailureThreshold: 2, OpenTimeout: time.Minute}, nil)
+
+	b.done(true)
+	b.done(false)
+	b.done(true)
+
+	if b.current() != BREAKER_CLOSED {
+		t.Fatalf("state = %s after non-consecutive failures, want closed", b.current())
+	}
+}
+
+func TestBreakerCancelledProbe(t *testing.T) {
+	o := defaultOptions()
+	o.retry = RetryPolicy{MaxAttempts: 1}
+	o.breaker = BreakerSettings{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond}
+	conn := newConn(nil, o)
+
+	conn.breaker.allow()
+	conn.breaker.done(true)
+	time.Sleep(20 * time.Millisecond)
+
+	// Пробный запрос отменен вызывающей стороной
+	ctx, cancel := context.WithCancel(context.Background())
+	err := conn.do(ctx, "read", true, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if err == nil {
+		t.Fatal("cancelled request succeeded")
+	}
+
+	if state := conn.breaker.current(); state != BREAKER_HALF_OPEN {
+		t.Fatalf("state = %s after cancelled probe, want half-open", state)
+	}
+
+	// Следующий запрос снова пробный
+	called := false
+	err = conn.do(context.Background(), "read", true, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Fatalf("probe after cancelled probe is rejected: %v", err)
+	}
+
+	if state := conn.breaker.current(); state != BREAKER_CLOSED {
+		t.Fatalf("state = %s after successful probe, want closed", state)
+	}
+}
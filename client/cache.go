@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// configRecord struct
+//
+// Конфигурация, полученная от сервера, вместе с номером версии и ETag.
+type configRecord struct {
+	Service   string          `json:"service"`
+	Version   int             `json:"version"`
+	ETag      string          `json:"etag"`
+	FetchedAt time.Time       `json:"fetchedAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// fileCache struct
+//
+// Локальная копия последней полученной конфигурации каждого сервиса,
+// используется, если сервер недоступен.
+type fileCache struct {
+	dir string
+}
+
+// path function
+//
+// Для закрепленной версии конфигурации используется отдельный файл.
+func (f *fileCache) path(service string, version int) string {
+	name := url.PathEscape(service)
+	if version > 0 {
+		name = fmt.Sprintf("%s@%d", name, version)
+	}
+
+	return filepath.Join(f.dir, name+".json")
+}
+
+// load function
+func (f *fileCache) load(service string, version int) (*configRecord, error) {
+	data, err := os.ReadFile(f.path(service, version))
+	if err != nil {
+		return nil, err
+	}
+
+	record := &configRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+
+	if record.Service != service || len(record.Data) == 0 {
+		return nil, fmt.Errorf("cache file %s doesn't contain config of service %q", f.path(service, version), service)
+	}
+
+	return record, nil
+}
+
+// store function
+//
+// Запись во временный файл с последующим переименованием, чтобы
+// при сбое в кэше осталась предыдущая или новая версия целиком.
+func (f *fileCache) store(version int, record *configRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	// Конфигурация может содержать секретные значения
+	if err := os.MkdirAll(f.dir, 0o700); err != nil {
+		return err
+	}
+
+	path := f.path(record.Service, version)
+
+	tmp, err := os.CreateTemp(f.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
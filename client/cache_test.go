@@ -0,0 +1,137 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileCacheStoreLoad(t *testing.T) {
+	f := &fileCache{dir: filepath.Join(t.TempDir(), "cache")}
+
+	record := &configRecord{
+		Service:   "svc/a",
+		Version:   3,
+		ETag:      `"e3"`,
+		FetchedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      json.RawMessage(`{"a":1}`),
+	}
+
+	if err := f.store(0, record); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.load("svc/a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 3 || got.ETag != `"e3"` || string(got.Data) != `{"a":1}` || !got.FetchedAt.Equal(record.FetchedAt) {
+		t.Fatalf("loaded record = %+v", got)
+	}
+
+	info, err := os.Stat(f.path("svc/a", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("cache file mode = %o, want 600", perm)
+	}
+
+	// Закрепленная версия хранится отдельно
+	if _, err := f.load("svc/a", 3); err == nil {
+		t.Fatal("pinned version is loaded from the latest version file")
+	}
+}
+
+func TestFileCacheRejectsOtherService(t *testing.T) {
+	f := &fileCache{dir: t.TempDir()}
+
+	data, _ := json.Marshal(&configRecord{Service: "other", Data: json.RawMessage(`{}`)})
+	if err := os.WriteFile(f.path("svc", 0), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.load("svc", 0); err == nil {
+		t.Fatal("config of another service is loaded")
+	}
+}
+
+func TestFileCacheAtomicStore(t *testing.T) {
+	f := &fileCache{dir: t.TempDir()}
+
+	// Большая конфигурация, чтобы запись не укладывалась в один вызов
+	payload := func(i int) json.RawMessage {
+		values := make([]int, 5000)
+		for j := range values {
+			values[j] = i
+		}
+		data, _ := json.Marshal(map[string]interface{}{"writer": i, "values": values})
+		return data
+	}
+
+	if err := f.store(0, &configRecord{Service: "svc", Data: payload(0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				if err := f.store(0, &configRecord{Service: "svc", Data: payload(i)}); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 50; n++ {
+			record, err := f.load("svc", 0)
+			if err != nil {
+				errs <- fmt.Errorf("load during store: %w", err)
+				continue
+			}
+
+			var doc struct {
+				Writer int   `json:"writer"`
+				Values []int `json:"values"`
+			}
+			if err := json.Unmarshal(record.Data, &doc); err != nil {
+				errs <- err
+				continue
+			}
+			for _, v := range doc.Values {
+				if v != doc.Writer {
+					errs <- fmt.Errorf("cache mixes writes of %d and %d", doc.Writer, v)
+					break
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// Временные файлы не остаются в каталоге
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("cache dir contains %d files, want 1", len(entries))
+	}
+}
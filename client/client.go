@@ -5,10 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"io/fs"
+	"sync"
 	"time"
 )
 
@@ -19,15 +17,72 @@ type ConfigDataJSON struct {
 }
 
 // ConfigClient struct
+//
+// Клиент конфигурации одного сервиса. Клиенты разных сервисов,
+// созданные функцией Conn.Service, используют общее соединение.
 type ConfigClient struct {
-	uri      string
+	conn *Conn
+
+	// Клиент, созданный функциями New и Connect, закрывает соединение
+	// при закрытии
+	owner     bool
+	closeOnce sync.Once
+
+	// Состояние клиента защищено mu, так как автоматическое обновление
+	// конфигурации выполняется в отдельной горутине
+	mu       sync.Mutex
 	service  string
 	version  int
 	cfg      []byte
-	client   *http.Client
 	callback UpdateCallback
-	refresh  *time.Ticker
-	done     chan bool
+	subs     []*subscription
+	keys     []*keySubscription
+	stop     chan struct{}
+
+	// Состояние локальной копии конфигурации
+	cached []byte
+	stale  bool
+}
+
+// transport interface
+//
+// Способ обмена данными с сервером конфигураций (HTTP или gRPC).
+type transport interface {
+	read(ctx context.Context, params *readParams) (*configRecord, error)
+	write(ctx context.Context, create bool, data *ConfigDataJSON, ifMatch string) (*ConfigVersion, error)
+	remove(ctx context.Context, service string, version int) error
+	services(ctx context.Context) ([]string, error)
+	versions(ctx context.Context, service string) ([]*ConfigVersion, error)
+	close() error
+}
+
+// readParams struct
+type readParams struct {
+	service string
+	version int
+
+	// Запрос автоматического обновления конфигурации
+	refresh bool
+
+	// Секретные значения в маркерах {"$secret": value}
+	marked bool
+}
+
+// watcher interface
+//
+// Транспорт, получающий новые версии конфига от сервера без опроса.
+type watcher interface {
+	watch(ctx context.Context, service string, fn func(*configRecord)) error
+}
+
+// ConfigVersion struct
+//
+// Версия конфига, созданная запросом на запись.
+type ConfigVersion struct {
+	Service   string    `json:"service"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	ETag      string    `json:"etag"`
 }
 
 // UpdateCallback type
@@ -35,39 +90,127 @@ type UpdateCallback func([]byte)
 
 const EMPTY_STRING = ""
 
+const (
+	REFRESH_HEADER = "X-Config-Refresh"
+	VERSION_HEADER = "X-Config-Version"
+
+	// Параметр чтения конфигурации с секретными значениями в маркерах
+	SECRETS_PARAM  = "secrets"
+	SECRETS_MARKED = "marked"
+)
+
 var ErrEmptyServiceName = errors.New("empty service name")
 
-// Connect function
-func Connect(uri string, service string, version ...int) (*ConfigClient, error) {
-	cl := &http.Client{
-		Timeout: 5 * time.Second,
-	}
+// New function
+//
+// Создает клиент сервера конфигураций, доступного по HTTP по адресу uri.
+// Имя сервиса задается параметром WithService, дополнительные серверы
+// для переключения при недоступности основного – параметром WithEndpoints.
+func New(uri string, opts ...Option) (*ConfigClient, error) {
+	o := applyOptions(opts)
 
-	if service == EMPTY_STRING {
+	if o.service == EMPTY_STRING {
 		return nil, ErrEmptyServiceName
 	}
 
-	ver := 0
+	conn, err := open(uri, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.handle(o.service, o.version, true), nil
+}
+
+// Connect function
+//
+// Клиент с параметрами подключения по умолчанию, остальные параметры
+// задаются при создании клиента функцией New.
+func Connect(uri string, service string, version ...int) (*ConfigClient, error) {
+	return New(uri, WithService(service), WithVersion(versionParam(version)))
+}
+
+// versionParam function
+func versionParam(version []int) int {
 	if len(version) > 0 {
-		ver = version[0]
+		return version[0]
 	}
 
-	return &ConfigClient{
-		uri:     uri,
-		service: service,
-		version: ver,
-		client:  cl,
-		done:    make(chan bool),
-	}, nil
+	return 0
+}
+
+// Stop function
+//
+// Останавливает автоматическое обновление конфигурации,
+// после чего можно назначить новую функцию обновления.
+func (c *ConfigClient) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+		c.callback = nil
+		c.subs = nil
+		c.keys = nil
+	}
+}
+
+// Close function
+//
+// Останавливает автоматическое обновление конфигурации. Клиент,
+// созданный функциями New и Connect, также закрывает соединение
+// с сервером, для клиентов Conn.Service соединение закрывается
+// функцией Conn.Close.
+func (c *ConfigClient) Close() error {
+	var err error
+
+	c.closeOnce.Do(func() {
+		c.Stop()
+		if c.owner {
+			err = c.conn.Close()
+		}
+	})
+
+	return err
+}
+
+// Conn function
+//
+// Соединение с сервером, которое можно использовать для клиентов
+// конфигурации других сервисов.
+func (c *ConfigClient) Conn() *Conn {
+	return c.conn
+}
+
+// Version function
+//
+// Клиент конфигурации того же сервиса с номером версии version.
+// У нового клиента собственное автоматическое обновление конфигурации.
+func (c *ConfigClient) Version(version int) *ConfigClient {
+	service, _ := c.serviceParams()
+	return c.conn.handle(service, version, false)
+}
+
+// Stats function
+func (c *ConfigClient) Stats() Stats {
+	return c.conn.Stats()
 }
 
 // SetServiceParams function
+//
+// Deprecated: изменяет клиента, который может использоваться другими
+// горутинами, для других сервисов и версий используйте Conn.Service
+// и ConfigClient.Version.
 func (c *ConfigClient) SetServiceParams(service string, version ...int) error {
 	if service == EMPTY_STRING {
 		return ErrEmptyServiceName
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.service = service
+	c.cached = nil
 
 	if len(version) > 0 && version[0] > 0 {
 		c.version = version[0]
@@ -76,37 +219,186 @@ func (c *ConfigClient) SetServiceParams(service string, version ...int) error {
 	return nil
 }
 
+// serviceParams function
+func (c *ConfigClient) serviceParams() (string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.service, c.version
+}
+
 // CreateConfig function
-func (c *ConfigClient) CreateConfig(ctx context.Context, data interface{}) error {
-	return c.doPostOrPutRequest(ctx, http.MethodPost, data)
+func (c *ConfigClient) CreateConfig(ctx context.Context, data interface{}) (*ConfigVersion, error) {
+	service, _ := c.serviceParams()
+
+	cfgData, err := c.formatPostData(service, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.write(ctx, true, cfgData, EMPTY_STRING)
 }
 
 // readConfig function
-func (c *ConfigClient) readConfig(ctx context.Context) ([]byte, error) {
-	req, err := c.makeGetOrDeleteRequest(ctx, http.MethodGet)
+func (c *ConfigClient) readConfig(ctx context.Context, refresh bool) ([]byte, error) {
+	service, version := c.serviceParams()
+	if service == EMPTY_STRING {
+		return nil, ErrEmptyServiceName
+	}
+
+	var record *configRecord
+
+	err := c.conn.do(ctx, "read", true, func(ctx context.Context) error {
+		var err error
+		record, err = c.conn.transport.read(ctx, &readParams{service: service, version: version, refresh: refresh})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		// Локальная копия используется только при явном чтении
+		// конфигурации, автоматическое обновление ее не использует
+		if refresh || !fallback(err) {
+			return nil, err
+		}
+
+		return c.readCache(service, version, err)
+	}
+
+	c.remember(version, record)
+
+	return record.Data, nil
+}
+
+// Stale function
+//
+// Возвращает true, если последнее чтение конфигурации вернуло локальную
+// копию из-за недоступности сервера.
+func (c *ConfigClient) Stale() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stale
+}
+
+// remember function
+//
+// Сохраняет полученную от сервера конфигурацию в локальную копию.
+func (c *ConfigClient) remember(version int, record *configRecord) {
+	c.mu.Lock()
+	c.stale = false
+
+	if c.conn.cache == nil || bytes.Equal(record.Data, c.cached) {
+		c.mu.Unlock()
+		return
+	}
+	c.cached = record.Data
+	c.mu.Unlock()
+
+	record.FetchedAt = time.Now().UTC()
+
+	if err := c.conn.cache.store(version, record); err != nil {
+		c.cacheError(err)
+	}
+}
+
+// readCache function
+func (c *ConfigClient) readCache(service string, version int, reqErr error) ([]byte, error) {
+	if c.conn.cache == nil {
+		return nil, reqErr
 	}
 
-	resp, err := c.client.Do(req)
+	record, err := c.conn.cache.load(service, version)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, fs.ErrNotExist) {
+			c.cacheError(err)
+		}
+		return nil, reqErr
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request aborted with status: %s", resp.Status)
+	c.mu.Lock()
+	c.stale = true
+	c.mu.Unlock()
+
+	if hook := c.conn.opts.hooks.OnStale; hook != nil {
+		hook(record.Version, record.FetchedAt, reqErr)
 	}
 
-	return io.ReadAll(resp.Body)
+	return record.Data, nil
+}
+
+// cacheError function
+func (c *ConfigClient) cacheError(err error) {
+	if hook := c.conn.opts.hooks.OnCacheError; hook != nil {
+		hook(err)
+		return
+	}
+
+	c.conn.opts.logger.Printf("%v", err)
+}
+
+// fallback function
+//
+// Ошибка, при которой вместо ответа сервера используется локальная копия.
+func fallback(err error) bool {
+	return unavailable(err) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// write function
+func (c *ConfigClient) write(ctx context.Context, create bool, data *ConfigDataJSON, ifMatch string) (*ConfigVersion, error) {
+	op := "update"
+	if create {
+		op = "create"
+	}
+
+	var created *ConfigVersion
+
+	err := c.conn.do(ctx, op, false, func(ctx context.Context) error {
+		var err error
+		created, err = c.conn.transport.write(ctx, create, data, ifMatch)
+		return err
+	})
+
+	return created, err
 }
 
 // ReadConfigBytes function
 func (c *ConfigClient) ReadConfigBytes(ctx context.Context) ([]byte, error) {
-	var err error
-	c.cfg, err = c.readConfig(ctx)
+	cfgBytes, err := c.readConfig(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cfg = cfgBytes
+	c.mu.Unlock()
 
-	return c.cfg, err
+	return cfgBytes, nil
+}
+
+// ReadMarkedConfig function
+//
+// Читает конфигурацию с открытыми секретными значениями в маркерах
+// {"$secret": value}, такую конфигурацию можно записать обратно без
+// потери секретов. Требует разрешения read-secrets, без него возвращается
+// ошибка ErrForbidden. Возвращает также сведения о прочитанной версии
+// с ETag для UpdateConfigIfMatch. Локальная копия не используется.
+func (c *ConfigClient) ReadMarkedConfig(ctx context.Context) ([]byte, *ConfigVersion, error) {
+	service, version := c.serviceParams()
+	if service == EMPTY_STRING {
+		return nil, nil, ErrEmptyServiceName
+	}
+
+	var record *configRecord
+
+	err := c.conn.do(ctx, "read", true, func(ctx context.Context) error {
+		var err error
+		record, err = c.conn.transport.read(ctx, &readParams{service: service, version: version, marked: true})
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return record.Data, &ConfigVersion{Service: service, Version: record.Version, ETag: record.ETag}, nil
 }
 
 // ReadAndDecodeConfig function
@@ -120,107 +412,283 @@ func (c *ConfigClient) ReadAndDecodeConfig(ctx context.Context, data interface{}
 }
 
 // UpdateConfig function
-func (c *ConfigClient) UpdateConfig(ctx context.Context, data interface{}) error {
-	return c.doPostOrPutRequest(ctx, http.MethodPut, data)
-}
+func (c *ConfigClient) UpdateConfig(ctx context.Context, data interface{}) (*ConfigVersion, error) {
+	service, _ := c.serviceParams()
 
-// DeleteConfig function
-func (c *ConfigClient) DeleteConfig(ctx context.Context) error {
-	req, err := c.makeGetOrDeleteRequest(ctx, http.MethodDelete)
+	cfgData, err := c.formatPostData(service, data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := c.client.Do(req)
+	return c.write(ctx, false, cfgData, EMPTY_STRING)
+}
+
+// UpdateConfigIfMatch function
+//
+// Создает новую версию конфигурации, только если ETag последней версии
+// равен etag, иначе возвращает ошибку ErrPreconditionFailed. Позволяет
+// изменить конфигурацию, прочитанную ReadMarkedConfig, не потеряв
+// изменений, сохраненных другими клиентами после чтения. Сервер выдает
+// слабые ETag и сравнивает etag с ними как строку, вместо строгого
+// сравнения If-Match по RFC 7232, поэтому etag передается в том виде,
+// в котором получен от сервера, вместе с префиксом W/.
+func (c *ConfigClient) UpdateConfigIfMatch(ctx context.Context, data interface{}, etag string) (*ConfigVersion, error) {
+	service, _ := c.serviceParams()
+
+	cfgData, err := c.formatPostData(service, data)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return c.write(ctx, false, cfgData, etag)
+}
+
+// DeleteConfig function
+func (c *ConfigClient) DeleteConfig(ctx context.Context) error {
+	service, version := c.serviceParams()
+	if service == EMPTY_STRING {
+		return ErrEmptyServiceName
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
-		return nil
+	return c.conn.do(ctx, "delete", true, func(ctx context.Context) error {
+		return c.conn.transport.remove(ctx, service, version)
+	})
+}
+
+// Versions function
+//
+// Сведения о всех версиях конфига сервиса по возрастанию номера версии.
+func (c *ConfigClient) Versions(ctx context.Context) ([]*ConfigVersion, error) {
+	service, _ := c.serviceParams()
+	if service == EMPTY_STRING {
+		return nil, ErrEmptyServiceName
 	}
 
-	return fmt.Errorf("request aborted with status: %s", resp.Status)
+	var versions []*ConfigVersion
+
+	err := c.conn.do(ctx, "versions", true, func(ctx context.Context) error {
+		var err error
+		versions, err = c.conn.transport.versions(ctx, service)
+		return err
+	})
+
+	return versions, err
 }
 
 // AssignRefreshCallback function
+//
+// Если автоматическое обновление уже запущено функцией OnKeyChange,
+// используется его период. Для транспорта gRPC новые версии конфига приходят от сервера
+// без опроса, а period задает паузу перед переподключением.
 func (c *ConfigClient) AssignRefreshCallback(period time.Duration, cb UpdateCallback) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.callback != nil {
 		return errors.New("callback is already assigned")
 	}
 
 	c.callback = cb
-	c.refresh = time.NewTicker(period)
-
-	go func() {
-		for {
-			select {
-			case <-c.done:
-				c.refresh.Stop()
-				return
-			case <-c.refresh.C:
-				if cfgBytes, err := c.readConfig(context.Background()); err == nil {
-					if !bytes.Equal(cfgBytes, c.cfg) {
-						c.cfg = cfgBytes
-						c.callback(c.cfg)
-					}
-				} else {
-					log.Println(err)
-				}
-			}
-		}
-	}()
+	c.startRefresh(period)
 
 	return nil
 }
 
-func (c *ConfigClient) formatPostData(service string, data interface{}) ([]byte, error) {
-	if service == "" {
-		return nil, errors.New("empty service name")
+// subscription struct
+//
+// Функция обновления с собственной отменой, например для Live.
+type subscription struct {
+	fn UpdateCallback
+}
+
+// subscribe function
+//
+// Добавляет функцию обновления, не занимая AssignRefreshCallback, и
+// запускает автоматическое обновление. Возвращает функцию отмены
+// подписки, которая не останавливает обновление для других подписок.
+func (c *ConfigClient) subscribe(period time.Duration, fn UpdateCallback) func() {
+	sub := &subscription{fn: fn}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Список подписок не изменяется, а заменяется, так как
+	// цикл обновления использует его без блокировки
+	c.subs = append(append([]*subscription{}, c.subs...), sub)
+	c.startRefresh(period)
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		subs := make([]*subscription, 0, len(c.subs))
+		for _, s := range c.subs {
+			if s != sub {
+				subs = append(subs, s)
+			}
+		}
+		c.subs = subs
 	}
+}
 
-	if data == nil {
-		return nil, errors.New("empty config data")
+// startRefresh function
+//
+// Запускает автоматическое обновление конфигурации, если оно еще
+// не запущено. Вызывается с заблокированным mu.
+func (c *ConfigClient) startRefresh(period time.Duration) {
+	if c.stop != nil {
+		return
 	}
 
-	dataBytes, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
+	c.stop = make(chan struct{})
+
+	// Функции обновления вызываются только для конфигурации,
+	// отличной от последней полученной клиентом
+	u := &updater{client: c, last: c.cfg, stop: c.stop}
+
+	// Поток Watch передает только последние версии конфигурации
+	if w, ok := c.conn.transport.(watcher); ok && c.version == 0 {
+		go c.watch(w, period, u)
+		return
 	}
 
-	cfgData := &ConfigDataJSON{
-		Service: service,
-		Data:    dataBytes,
+	go c.poll(period, u)
+}
+
+// poll function
+func (c *ConfigClient) poll(period time.Duration, u *updater) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.stop:
+			return
+		case <-c.conn.done:
+			return
+		case <-ticker.C:
+			if cfgBytes, err := c.readConfig(context.Background(), true); err == nil {
+				u.update(cfgBytes)
+			} else {
+				c.refreshError(err)
+			}
+		}
 	}
+}
+
+// watch function
+func (c *ConfigClient) watch(w watcher, period time.Duration, u *updater) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	return json.Marshal(cfgData)
+	go func() {
+		select {
+		case <-u.stop:
+			cancel()
+		case <-c.conn.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		service, _ := c.serviceParams()
+
+		err := w.watch(ctx, service, func(record *configRecord) {
+			c.remember(0, record)
+			u.update(record.Data)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.refreshError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(period):
+		}
+	}
 }
 
-// doPostOrPutRequest function
-func (c *ConfigClient) doPostOrPutRequest(ctx context.Context, method string, data interface{}) error {
-	cfgData, err := c.formatPostData(c.service, data)
-	if err != nil {
-		return err
+// refreshError function
+func (c *ConfigClient) refreshError(err error) {
+	if hook := c.conn.opts.hooks.OnRefreshError; hook != nil {
+		hook(err)
+		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, c.uri, bytes.NewReader(cfgData))
-	if err != nil {
-		return err
+	c.conn.opts.logger.Printf("%v", err)
+}
+
+// updater struct
+//
+// Состояние одного запуска автоматического обновления конфигурации.
+type updater struct {
+	client *ConfigClient
+	last   []byte
+	stop   chan struct{}
+}
+
+// update function
+func (u *updater) update(cfgBytes []byte) {
+	if bytes.Equal(cfgBytes, u.last) {
+		return
+	}
+	prev := u.last
+	u.last = cfgBytes
+
+	c := u.client
+	c.mu.Lock()
+
+	// Обновление после остановки не передается функции обновления
+	select {
+	case <-u.stop:
+		c.mu.Unlock()
+		return
+	default:
 	}
-	defer req.Body.Close()
 
-	req.Header.Add("Content-Type", "application/json")
+	c.cfg = cfgBytes
+	cb := c.callback
+	subs := c.subs
+	keys := c.keys
+	c.mu.Unlock()
 
-	if _, err = c.client.Do(req); err != nil {
-		return err
+	if cb != nil {
+		cb(cfgBytes)
 	}
 
-	return nil
+	for _, s := range subs {
+		s.fn(cfgBytes)
+	}
+
+	// Первая полученная версия служит исходной для сравнения значений
+	// подписок, поэтому о ее ключах не сообщается
+	if len(keys) > 0 && prev != nil {
+		c.notifyKeys(keys, prev, cfgBytes)
+	}
 }
 
-// makeGetOrDeleteRequest function
-func (c *ConfigClient) makeGetOrDeleteRequest(ctx context.Context, method string) (*http.Request, error) {
-	serviceUri := fmt.Sprintf("%s?service=%s&version=%d", c.uri, c.service, c.version)
-	return http.NewRequestWithContext(ctx, method, serviceUri, nil)
+// formatPostData function
+func (c *ConfigClient) formatPostData(service string, data interface{}) (*ConfigDataJSON, error) {
+	if service == EMPTY_STRING {
+		return nil, ErrEmptyServiceName
+	}
+
+	if data == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigDataJSON{
+		Service: service,
+		Data:    dataBytes,
+	}, nil
 }
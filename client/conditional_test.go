@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// markedServer function
+//
+// Тестовый сервер с одной версией конфигурации: чтение с параметром
+// secrets=marked требует токена, обновление с If-Match проверяет ETag.
+func markedServer(t *testing.T, token string, etag string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if r.URL.Query().Get(SECRETS_PARAM) != SECRETS_MARKED {
+				w.Write([]byte(`{"password":"[REDACTED]"}`))
+				return
+			}
+
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.Header().Set("Content-Type", "application/problem+json")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"status":403,"code":"forbidden"}`))
+				return
+			}
+
+			w.Header().Set(VERSION_HEADER, "1")
+			w.Header().Set("ETag", etag)
+			w.Write([]byte(`{"password":{"$secret":"p"}}`))
+			return
+		}
+
+		if match := r.Header.Get("If-Match"); match != etag {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(`{"status":412,"code":"precondition_failed"}`))
+			return
+		}
+
+		w.Header().Set(VERSION_HEADER, "2")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestReadMarkedConfig(t *testing.T) {
+	srv := markedServer(t, "secret-token", `"e1"`)
+	ctx := context.Background()
+
+	c, err := New(srv.URL+CONFIG_PATH, WithService("svc"), WithBearerToken("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, _, err := c.ReadMarkedConfig(ctx); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("ReadMarkedConfig() error = %v, want %v", err, ErrForbidden)
+	}
+
+	c, err = New(srv.URL+CONFIG_PATH, WithService("svc"), WithBearerToken("secret-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	data, ver, err := c.ReadMarkedConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"password":{"$secret":"p"}}` || ver.Version != 1 || ver.ETag != `"e1"` {
+		t.Fatalf("ReadMarkedConfig() = %s, %+v", data, ver)
+	}
+}
+
+func TestUpdateConfigIfMatch(t *testing.T) {
+	srv := markedServer(t, "secret-token", `"e2"`)
+	ctx := context.Background()
+
+	c, err := New(srv.URL+CONFIG_PATH, WithService("svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	data := json.RawMessage(`{"a":1}`)
+
+	_, err = c.UpdateConfigIfMatch(ctx, data, `"e1"`)
+	if !errors.Is(err, ErrPreconditionFailed) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("UpdateConfigIfMatch() error = %v, want %v", err, ErrPreconditionFailed)
+	}
+
+	created, err := c.UpdateConfigIfMatch(ctx, data, `"e2"`)
+	if err != nil || created.Version != 2 {
+		t.Fatalf("UpdateConfigIfMatch() = %+v, %v", created, err)
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Conn struct
+//
+// Соединение с сервером конфигураций, общее для клиентов конфигураций
+// разных сервисов. Политика повторных попыток, автоматический выключатель,
+// локальная копия и счетчики запросов также общие.
+type Conn struct {
+	transport transport
+	opts      *options
+	breaker   *breaker
+	stats     clientStats
+	cache     *fileCache
+	closeOnce sync.Once
+
+	// Закрывается при закрытии соединения, автоматическое обновление
+	// конфигурации всех клиентов при этом останавливается
+	done chan struct{}
+}
+
+// Open function
+//
+// Подключение к серверу конфигураций по HTTP без привязки к сервису,
+// клиенты конфигураций сервисов создаются функцией Service.
+func Open(uri string, opts ...Option) (*Conn, error) {
+	return open(uri, applyOptions(opts))
+}
+
+// open function
+func open(uri string, o *options) (*Conn, error) {
+	eps, err := newEndpoints(append([]string{uri}, o.endpoints...), o.hooks.OnFailover)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := o.httpClient
+	if cl == nil {
+		// Транспорт передает контекст трассировки OpenTelemetry в заголовках
+		// запросов, используется глобальный TracerProvider приложения
+		cl = &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		}
+	}
+
+	t := &httpTransport{
+		endpoints: eps,
+		client:    cl,
+		header:    o.headers(),
+		timeout:   o.timeout,
+	}
+
+	return newConn(t, o), nil
+}
+
+// newConn function
+func newConn(t transport, o *options) *Conn {
+	conn := &Conn{
+		transport: t,
+		opts:      o,
+		breaker:   newBreaker(o.breaker, o.hooks.OnBreakerStateChange),
+		done:      make(chan struct{}),
+	}
+
+	if o.cacheDir != EMPTY_STRING {
+		conn.cache = &fileCache{dir: o.cacheDir}
+	}
+
+	return conn
+}
+
+// Service function
+//
+// Клиент последней версии конфигурации сервиса service. Каждый клиент
+// использует собственное автоматическое обновление конфигурации.
+func (conn *Conn) Service(service string) *ConfigClient {
+	return conn.handle(service, 0, false)
+}
+
+// ListServices function
+//
+// Имена сервисов, для которых на сервере есть конфигурация.
+func (conn *Conn) ListServices(ctx context.Context) ([]string, error) {
+	var services []string
+
+	err := conn.do(ctx, "list", true, func(ctx context.Context) error {
+		var err error
+		services, err = conn.transport.services(ctx)
+		return err
+	})
+
+	return services, err
+}
+
+// handle function
+func (conn *Conn) handle(service string, version int, owner bool) *ConfigClient {
+	return &ConfigClient{
+		conn:    conn,
+		owner:   owner,
+		service: service,
+		version: version,
+	}
+}
+
+// Close function
+//
+// Закрывает соединение с сервером и останавливает автоматическое
+// обновление конфигурации всех клиентов соединения.
+func (conn *Conn) Close() error {
+	var err error
+
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		err = conn.transport.close()
+	})
+
+	return err
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Время, в течение которого сервер после ошибки подключения
+// пропускается при выборе сервера
+const ENDPOINT_RETRY_DELAY = 5 * time.Second
+
+// Период повторного разрешения записей DNS SRV
+const SRV_REFRESH_PERIOD = time.Minute
+
+// Суффикс схемы адреса, задающего имя записи DNS SRV
+const SRV_SCHEME_SUFFIX = "+srv"
+
+var ErrNoEndpoints = errors.New("no config server endpoints")
+
+// endpoint struct
+type endpoint struct {
+	uri       string
+	downUntil time.Time
+}
+
+// srvName struct
+//
+// Адрес вида http+srv://_config._tcp.example.com/config, серверы
+// определяются по записям DNS SRV с именем из адреса.
+type srvName struct {
+	scheme string
+	name   string
+	path   string
+	query  string
+}
+
+// endpoints struct
+//
+// Список серверов конфигураций. Запросы отправляются последнему
+// серверу, ответившему без ошибок, при ошибке подключения сервер
+// временно пропускается и используется следующий.
+type endpoints struct {
+	onFailover func(from string, to string, err error)
+
+	mu         sync.Mutex
+	list       []*endpoint
+	preferred  string
+	static     []string
+	srv        []*srvName
+	resolvedAt time.Time
+}
+
+// newEndpoints function
+func newEndpoints(uris []string, onFailover func(string, string, error)) (*endpoints, error) {
+	e := &endpoints{onFailover: onFailover}
+
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasSuffix(u.Scheme, SRV_SCHEME_SUFFIX) {
+			e.static = append(e.static, uri)
+			e.list = append(e.list, &endpoint{uri: uri})
+			continue
+		}
+
+		e.srv = append(e.srv, &srvName{
+			scheme: strings.TrimSuffix(u.Scheme, SRV_SCHEME_SUFFIX),
+			name:   u.Hostname(),
+			path:   u.EscapedPath(),
+			query:  u.RawQuery,
+		})
+	}
+
+	if len(e.list) == 0 && len(e.srv) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	return e, nil
+}
+
+// candidates function
+//
+// Серверы в порядке попыток: сначала последний исправный, затем
+// остальные по порядку. Временно пропускаемые серверы используются,
+// только если исправных серверов нет.
+func (e *endpoints) candidates(ctx context.Context) ([]string, error) {
+	e.resolve(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.list) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	now := time.Now()
+
+	var healthy, down []string
+
+	for _, ep := range e.list {
+		switch {
+		case now.Before(ep.downUntil):
+			down = append(down, ep.uri)
+		case ep.uri == e.preferred:
+			healthy = append([]string{ep.uri}, healthy...)
+		default:
+			healthy = append(healthy, ep.uri)
+		}
+	}
+
+	return append(healthy, down...), nil
+}
+
+// success function
+func (e *endpoints) success(uri string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.preferred = uri
+
+	for _, ep := range e.list {
+		if ep.uri == uri {
+			ep.downUntil = time.Time{}
+		}
+	}
+}
+
+// failure function
+func (e *endpoints) failure(uri string, next string, err error) {
+	e.mu.Lock()
+	for _, ep := range e.list {
+		if ep.uri == uri {
+			ep.downUntil = time.Now().Add(ENDPOINT_RETRY_DELAY)
+		}
+	}
+	e.mu.Unlock()
+
+	if next != EMPTY_STRING && e.onFailover != nil {
+		e.onFailover(uri, next, err)
+	}
+}
+
+// resolve function
+//
+// Обновляет серверы, заданные записями DNS SRV. При ошибке разрешения
+// используется ранее полученный список.
+func (e *endpoints) resolve(ctx context.Context) {
+	e.mu.Lock()
+	if len(e.srv) == 0 || time.Since(e.resolvedAt) < SRV_REFRESH_PERIOD {
+		e.mu.Unlock()
+		return
+	}
+	e.resolvedAt = time.Now()
+	e.mu.Unlock()
+
+	var resolved []string
+
+	for _, s := range e.srv {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, EMPTY_STRING, EMPTY_STRING, s.name)
+		if err != nil {
+			continue
+		}
+
+		// Записи упорядочены по приоритету и весу
+		for _, r := range records {
+			resolved = append(resolved, s.uri(r))
+		}
+	}
+
+	if len(resolved) == 0 {
+		return
+	}
+
+	uris := append(append([]string{}, e.static...), resolved...)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Сохраняем состояние серверов, оставшихся в списке
+	known := make(map[string]*endpoint, len(e.list))
+	for _, ep := range e.list {
+		known[ep.uri] = ep
+	}
+
+	list := make([]*endpoint, 0, len(uris))
+	for _, uri := range uris {
+		if ep, ok := known[uri]; ok {
+			list = append(list, ep)
+			continue
+		}
+		list = append(list, &endpoint{uri: uri})
+	}
+
+	e.list = list
+}
+
+// uri function
+func (s *srvName) uri(r *net.SRV) string {
+	host := net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+
+	uri := fmt.Sprintf("%s://%s%s", s.scheme, host, s.path)
+	if s.query != EMPTY_STRING {
+		uri += "?" + s.query
+	}
+
+	return uri
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// configServer function
+//
+// Тестовый сервер, отвечающий на чтение конфигурации после задержки
+// delay кодом status.
+func configServer(t *testing.T, status int, delay time.Duration, hits *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		w.Header().Set(VERSION_HEADER, "1")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestEndpointFailoverOnTimeout(t *testing.T) {
+	var slowHits, fastHits atomic.Int32
+	slow := configServer(t, http.StatusOK, time.Second, &slowHits)
+	fast := configServer(t, http.StatusOK, 0, &fastHits)
+
+	var failovers atomic.Int32
+	c, err := New(slow.URL+CONFIG_PATH,
+		WithService("svc"),
+		WithEndpoints(fast.URL+CONFIG_PATH),
+		WithTimeout(100*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithHooks(Hooks{OnFailover: func(from string, to string, err error) { failovers.Add(1) }}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Время ожидания ответа истекает только для первого сервера
+	data, err := c.ReadConfigBytes(context.Background())
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != `{"ok":true}` {
+		t.Fatalf("data = %s", data)
+	}
+	if failovers.Load() != 1 {
+		t.Fatalf("failovers = %d, want 1", failovers.Load())
+	}
+
+	// Сервер, не ответивший вовремя, пропускается
+	if _, err = c.ReadConfigBytes(context.Background()); err != nil {
+		t.Fatalf("second read failed: %v", err)
+	}
+	if slowHits.Load() != 1 || fastHits.Load() != 2 {
+		t.Fatalf("hits: slow=%d fast=%d, want 1 and 2", slowHits.Load(), fastHits.Load())
+	}
+}
+
+func TestEndpointFailoverOnUnavailable(t *testing.T) {
+	var downHits, upHits atomic.Int32
+	down := configServer(t, http.StatusServiceUnavailable, 0, &downHits)
+	up := configServer(t, http.StatusOK, 0, &upHits)
+
+	c, err := New(down.URL+CONFIG_PATH,
+		WithService("svc"),
+		WithEndpoints(up.URL+CONFIG_PATH),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.ReadConfigBytes(context.Background()); err != nil {
+			t.Fatalf("read %d failed: %v", i, err)
+		}
+	}
+
+	if downHits.Load() != 1 || upHits.Load() != 3 {
+		t.Fatalf("hits: down=%d up=%d, want 1 and 3", downHits.Load(), upHits.Load())
+	}
+}
+
+func TestEndpointCandidates(t *testing.T) {
+	e, err := newEndpoints([]string{"http://a", "http://b", "http://c"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.success("http://b")
+	e.failure("http://a", "http://c", nil)
+
+	got, err := e.candidates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Сначала последний исправный сервер, недоступный – в конце
+	want := []string{"http://b", "http://c", "http://a"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("candidates = %v, want %v", got, want)
+		}
+	}
+}
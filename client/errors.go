@@ -0,0 +1,163 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки запросов к серверу конфигураций, для проверки используется errors.Is
+var (
+	ErrNotFound     = errors.New("config not found")
+	ErrConflict     = errors.New("config conflict")
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("permission denied")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+
+	// Уточненные ошибки, также соответствуют одной из общих ошибок выше
+	ErrAlreadyExists = errors.New("config already exists")
+	ErrInUse         = errors.New("config is in use")
+	ErrValidation    = errors.New("validation failed")
+
+	// Последняя версия конфигурации изменилась после чтения
+	ErrPreconditionFailed = errors.New("config was modified")
+)
+
+// codeErrors variable
+var codeErrors = map[string][]error{
+	"not_found":           {ErrNotFound},
+	"service_not_found":   {ErrNotFound},
+	"already_exists":      {ErrConflict, ErrAlreadyExists},
+	"config_in_use":       {ErrConflict, ErrInUse},
+	"empty_service_name":  {ErrBadRequest, ErrValidation},
+	"invalid_json":        {ErrBadRequest, ErrValidation},
+	"invalid_request":     {ErrBadRequest},
+	"invalid_version":     {ErrBadRequest, ErrValidation},
+	"secrets_disabled":    {ErrBadRequest},
+	"body_too_large":      {ErrBadRequest},
+	"unauthorized":        {ErrUnauthorized},
+	"forbidden":           {ErrForbidden},
+	"precondition_failed": {ErrConflict, ErrPreconditionFailed},
+	"rate_limited":        {ErrRateLimited},
+	"internal_error":      {ErrServer},
+}
+
+// InvalidParam struct
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Problem struct
+//
+// Описание ошибки в формате RFC 7807, возвращаемое сервером
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id"`
+
+	InvalidParams []InvalidParam `json:"invalid_params"`
+}
+
+// Error struct
+type Error struct {
+	StatusCode int
+	Problem    Problem
+
+	// Задержка перед повторным запросом из заголовка Retry-After
+	RetryAfter time.Duration
+}
+
+// Error function
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("request aborted with status: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+
+	if e.Problem.Code != EMPTY_STRING {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Problem.Code)
+	}
+
+	if e.Problem.Detail != EMPTY_STRING {
+		msg = fmt.Sprintf("%s: %s", msg, e.Problem.Detail)
+	}
+
+	for _, p := range e.Problem.InvalidParams {
+		msg = fmt.Sprintf("%s; %s %s", msg, p.Name, p.Reason)
+	}
+
+	if e.Problem.RequestID != EMPTY_STRING {
+		msg = fmt.Sprintf("%s [request_id=%s]", msg, e.Problem.RequestID)
+	}
+
+	return msg
+}
+
+// Is function
+func (e *Error) Is(target error) bool {
+	if errs, ok := codeErrors[e.Problem.Code]; ok {
+		for _, err := range errs {
+			if err == target {
+				return true
+			}
+		}
+		return false
+	}
+
+	return statusError(e.StatusCode) == target
+}
+
+// statusError function
+//
+// Ошибка по коду ответа, если сервер не вернул описание ошибки.
+func statusError(status int) error {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusConflict:
+		return ErrConflict
+	case status == http.StatusForbidden:
+		return ErrForbidden
+	case status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case status == http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return ErrBadRequest
+	}
+}
+
+// parseError function
+func parseError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+		body, err := io.ReadAll(resp.Body)
+		if err == nil {
+			_ = json.Unmarshal(body, &e.Problem)
+		}
+	}
+
+	if e.Problem.RequestID == EMPTY_STRING {
+		e.Problem.RequestID = resp.Header.Get("X-Request-ID")
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return e
+}
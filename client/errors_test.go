@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    http.Header
+		body      string
+		want      error
+		requestID string
+	}{
+		{
+			name:      "problem",
+			status:    http.StatusNotFound,
+			header:    http.Header{"Content-Type": {"application/problem+json"}},
+			body:      `{"status":404,"code":"service_not_found","detail":"service not found","request_id":"req-1"}`,
+			want:      ErrNotFound,
+			requestID: "req-1",
+		},
+		{
+			name:   "code over status",
+			status: http.StatusBadRequest,
+			header: http.Header{"Content-Type": {"application/problem+json"}},
+			body:   `{"status":400,"code":"rate_limited"}`,
+			want:   ErrRateLimited,
+		},
+		{
+			name:      "status only",
+			status:    http.StatusBadGateway,
+			header:    http.Header{"Content-Type": {"text/plain"}, "X-Request-Id": {"req-2"}},
+			body:      "bad gateway",
+			want:      ErrServer,
+			requestID: "req-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseError(&http.Response{
+				StatusCode: tt.status,
+				Header:     tt.header,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			})
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error %v is not %v", err, tt.want)
+			}
+
+			var e *Error
+			if !errors.As(err, &e) || e.StatusCode != tt.status || e.Problem.RequestID != tt.requestID {
+				t.Fatalf("error = %#v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-cloud-camp/internal/grpcjson"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/status"
+)
+
+const GRPC_SERVICE = "configserver.v1.ConfigService"
+
+// Ключи метаданных ответов сервера
+const (
+	GRPC_REQUEST_ID_KEY = "x-request-id"
+	GRPC_ERROR_CODE_KEY = "x-error-code"
+	GRPC_IF_MATCH_KEY   = "if-match"
+)
+
+// grpcConfig struct
+type grpcConfig struct {
+	Service   string          `json:"service"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+	ETag      string          `json:"etag"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// record function
+func (c *grpcConfig) record() *configRecord {
+	return &configRecord{
+		Service: c.Service,
+		Version: c.Version,
+		ETag:    c.ETag,
+		Data:    c.Data,
+	}
+}
+
+// grpcServiceRequest struct
+type grpcServiceRequest struct {
+	Service string `json:"service"`
+	Version int    `json:"version,omitempty"`
+	Secrets string `json:"secrets,omitempty"`
+}
+
+// grpcStatus variable
+//
+// Коды ответа HTTP, соответствующие статусам gRPC.
+var grpcStatus = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.FailedPrecondition: http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.Aborted:            http.StatusPreconditionFailed,
+}
+
+// grpcTransport struct
+type grpcTransport struct {
+	conn *grpc.ClientConn
+	md   metadata.MD
+
+	// Время ожидания ответа на один вызов, кроме потока Watch
+	timeout time.Duration
+}
+
+// ConnectGRPC function
+//
+// Подключение к серверу конфигураций по gRPC, target в формате host:port.
+// Автоматическое обновление конфигурации использует поток Watch.
+func ConnectGRPC(target string, service string, version ...int) (*ConfigClient, error) {
+	return NewGRPC(target, WithService(service), WithVersion(versionParam(version)))
+}
+
+// NewGRPC function
+//
+// Создает клиент сервера конфигураций, доступного по gRPC, параметры
+// подключения задаются так же, как для функции New.
+func NewGRPC(target string, opts ...Option) (*ConfigClient, error) {
+	o := applyOptions(opts)
+
+	if o.service == EMPTY_STRING {
+		return nil, ErrEmptyServiceName
+	}
+
+	conn, err := openGRPC(target, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.handle(o.service, o.version, true), nil
+}
+
+// OpenGRPC function
+//
+// Подключение к серверу конфигураций по gRPC без привязки к сервису.
+//
+// Дополнительные адреса серверов в формате host:port задаются параметром
+// WithEndpoints, соединение устанавливается с первым доступным сервером
+// и переключается на следующий при его недоступности.
+func OpenGRPC(target string, opts ...Option) (*Conn, error) {
+	return openGRPC(target, applyOptions(opts))
+}
+
+// openGRPC function
+func openGRPC(target string, o *options) (*Conn, error) {
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(grpcjson.Codec{})),
+	}
+
+	if o.userAgent != EMPTY_STRING {
+		dialOpts = append(dialOpts, grpc.WithUserAgent(o.userAgent))
+	}
+
+	if len(o.endpoints) > 0 {
+		target, dialOpts = grpcEndpoints(append([]string{target}, o.endpoints...), dialOpts)
+	}
+
+	conn, err := grpc.Dial(target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Заголовки запросов передаются в метаданных, User-Agent
+	// передается самим gRPC
+	md := metadata.MD{}
+	for key, values := range o.headers() {
+		if key != "User-Agent" {
+			md.Append(key, values...)
+		}
+	}
+
+	return newConn(&grpcTransport{conn: conn, md: md, timeout: o.timeout}, o), nil
+}
+
+// grpcEndpoints function
+//
+// Список серверов передается gRPC через собственный resolver, политика
+// балансировки pick_first использует серверы по порядку.
+func grpcEndpoints(targets []string, dialOpts []grpc.DialOption) (string, []grpc.DialOption) {
+	r := manual.NewBuilderWithScheme("configserver")
+
+	addrs := make([]resolver.Address, 0, len(targets))
+	for _, target := range targets {
+		addrs = append(addrs, resolver.Address{Addr: target})
+	}
+	r.InitialState(resolver.State{Addresses: addrs})
+
+	return r.Scheme() + ":///" + GRPC_SERVICE, append(dialOpts, grpc.WithResolvers(r))
+}
+
+// read function
+func (t *grpcTransport) read(ctx context.Context, params *readParams) (*configRecord, error) {
+	in := &grpcServiceRequest{Service: params.service, Version: params.version}
+	if params.marked {
+		in.Secrets = SECRETS_MARKED
+	}
+
+	cfg := &grpcConfig{}
+	if err := t.invoke(ctx, "Get", in, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg.record(), nil
+}
+
+// write function
+func (t *grpcTransport) write(ctx context.Context, create bool, data *ConfigDataJSON, ifMatch string) (*ConfigVersion, error) {
+	method := "Update"
+	if create {
+		method = "Create"
+	}
+
+	if ifMatch != EMPTY_STRING {
+		ctx = metadata.AppendToOutgoingContext(ctx, GRPC_IF_MATCH_KEY, ifMatch)
+	}
+
+	created := &ConfigVersion{}
+	if err := t.invoke(ctx, method, data, created); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// remove function
+func (t *grpcTransport) remove(ctx context.Context, service string, version int) error {
+	return t.invoke(ctx, "Delete", &grpcServiceRequest{Service: service, Version: version}, &struct{}{})
+}
+
+// services function
+func (t *grpcTransport) services(ctx context.Context) ([]string, error) {
+	list := struct {
+		Services []string `json:"services"`
+	}{}
+
+	if err := t.invoke(ctx, "List", &struct{}{}, &list); err != nil {
+		return nil, err
+	}
+
+	return list.Services, nil
+}
+
+// versions function
+func (t *grpcTransport) versions(ctx context.Context, service string) ([]*ConfigVersion, error) {
+	list := struct {
+		Versions []*ConfigVersion `json:"versions"`
+	}{}
+
+	if err := t.invoke(ctx, "Versions", &grpcServiceRequest{Service: service}, &list); err != nil {
+		return nil, err
+	}
+
+	return list.Versions, nil
+}
+
+// watch function
+func (t *grpcTransport) watch(ctx context.Context, service string, fn func(*configRecord)) error {
+	desc := &grpc.StreamDesc{StreamName: "Watch", ServerStreams: true}
+
+	stream, err := t.conn.NewStream(t.outgoing(ctx), desc, "/"+GRPC_SERVICE+"/Watch")
+	if err != nil {
+		return grpcError(err, nil, nil)
+	}
+
+	if err := stream.SendMsg(&grpcServiceRequest{Service: service}); err != nil {
+		return grpcError(err, nil, nil)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return grpcError(err, nil, nil)
+	}
+
+	for {
+		cfg := &grpcConfig{}
+		if err := stream.RecvMsg(cfg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			header, _ := stream.Header()
+			return grpcError(err, header, stream.Trailer())
+		}
+
+		fn(cfg.record())
+	}
+}
+
+// close function
+func (t *grpcTransport) close() error {
+	return t.conn.Close()
+}
+
+// invoke function
+func (t *grpcTransport) invoke(ctx context.Context, method string, in interface{}, out interface{}) error {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	var header, trailer metadata.MD
+
+	err := t.conn.Invoke(t.outgoing(ctx), "/"+GRPC_SERVICE+"/"+method, in, out, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	return grpcError(err, header, trailer)
+}
+
+// outgoing function
+func (t *grpcTransport) outgoing(ctx context.Context) context.Context {
+	if len(t.md) == 0 {
+		return ctx
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	return metadata.NewOutgoingContext(ctx, metadata.Join(md, t.md))
+}
+
+// grpcError function
+//
+// Преобразует статус gRPC в ошибку клиента с кодом ошибки сервера.
+func grpcError(err error, header metadata.MD, trailer metadata.MD) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	statusCode, ok := grpcStatus[st.Code()]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+
+	e := &Error{
+		StatusCode: statusCode,
+		Problem: Problem{
+			Title:  http.StatusText(statusCode),
+			Status: statusCode,
+			Detail: st.Message(),
+		},
+	}
+
+	if values := trailer.Get(GRPC_ERROR_CODE_KEY); len(values) > 0 {
+		e.Problem.Code = values[0]
+	}
+
+	if values := header.Get(GRPC_REQUEST_ID_KEY); len(values) > 0 {
+		e.Problem.RequestID = values[0]
+	}
+
+	return e
+}
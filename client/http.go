@@ -0,0 +1,343 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Путь ресурсов конфигурации в адресе сервера и путь списка сервисов API v1
+const (
+	CONFIG_PATH   = "/config"
+	SERVICES_PATH = "/v1/services"
+)
+
+// httpTransport struct
+type httpTransport struct {
+	endpoints *endpoints
+	client    *http.Client
+	header    http.Header
+
+	// Время ожидания ответа каждого сервера
+	timeout time.Duration
+}
+
+// cancelBody struct
+//
+// Тело ответа, при закрытии которого освобождается контекст запроса.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close function
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// read function
+func (t *httpTransport) read(ctx context.Context, params *readParams) (*configRecord, error) {
+	resp, err := t.send(ctx, true, func(uri string) (*http.Request, error) {
+		req, err := makeGetOrDeleteRequest(ctx, http.MethodGet, uri, params.service, params.version)
+		if err != nil {
+			return nil, err
+		}
+
+		// Запросы автоматического обновления помечаются отдельным заголовком
+		if params.refresh {
+			req.Header.Set(REFRESH_HEADER, "true")
+		}
+
+		if params.marked {
+			query := req.URL.Query()
+			query.Set(SECRETS_PARAM, SECRETS_MARKED)
+			req.URL.RawQuery = query.Encode()
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseError(resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &configRecord{
+		Service: params.service,
+		ETag:    resp.Header.Get("ETag"),
+		Data:    data,
+	}
+	record.Version, _ = strconv.Atoi(resp.Header.Get(VERSION_HEADER))
+
+	return record, nil
+}
+
+// write function
+func (t *httpTransport) write(ctx context.Context, create bool, data *ConfigDataJSON, ifMatch string) (*ConfigVersion, error) {
+	method := http.MethodPut
+	if create {
+		method = http.MethodPost
+	}
+
+	cfgData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.send(ctx, false, func(uri string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, uri, bytes.NewReader(cfgData))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Content-Type", "application/json")
+
+		if ifMatch != EMPTY_STRING {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, parseError(resp)
+	}
+
+	// Сведения о созданной версии сервер возвращает в теле ответа,
+	// номер версии и ETag дублируются в заголовках
+	created := &ConfigVersion{}
+	if err := json.NewDecoder(resp.Body).Decode(created); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if created.Service == EMPTY_STRING {
+		created.Service = data.Service
+	}
+	if created.Version == 0 {
+		created.Version, _ = strconv.Atoi(resp.Header.Get(VERSION_HEADER))
+	}
+	if created.ETag == EMPTY_STRING {
+		created.ETag = resp.Header.Get("ETag")
+	}
+
+	return created, nil
+}
+
+// remove function
+func (t *httpTransport) remove(ctx context.Context, service string, version int) error {
+	resp, err := t.send(ctx, true, func(uri string) (*http.Request, error) {
+		return makeGetOrDeleteRequest(ctx, http.MethodDelete, uri, service, version)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	return parseError(resp)
+}
+
+// services function
+func (t *httpTransport) services(ctx context.Context) ([]string, error) {
+	list := struct {
+		Services []string `json:"services"`
+	}{}
+
+	if err := t.getJSON(ctx, EMPTY_STRING, &list); err != nil {
+		return nil, err
+	}
+
+	return list.Services, nil
+}
+
+// versions function
+func (t *httpTransport) versions(ctx context.Context, service string) ([]*ConfigVersion, error) {
+	list := struct {
+		Versions []*ConfigVersion `json:"versions"`
+	}{}
+
+	if err := t.getJSON(ctx, "/"+url.PathEscape(service)+"/versions", &list); err != nil {
+		return nil, err
+	}
+
+	return list.Versions, nil
+}
+
+// getJSON function
+//
+// Запрос к ресурсу API v1 по пути path относительно списка сервисов.
+func (t *httpTransport) getJSON(ctx context.Context, path string, v interface{}) error {
+	resp, err := t.send(ctx, true, func(uri string) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, servicesURL(uri)+path, nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return parseError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// close function
+func (t *httpTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// send function
+//
+// Отправляет запрос серверам по очереди, пока один из них не ответит.
+// Следующий сервер используется при ошибке подключения, а для
+// идемпотентных запросов – также при любой ошибке сети (в том числе
+// истечении времени ожидания ответа) и ответах 502, 503 и 504.
+func (t *httpTransport) send(ctx context.Context, idempotent bool, build func(uri string) (*http.Request, error)) (*http.Response, error) {
+	uris, err := t.endpoints.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, uri := range uris {
+		req, err := build(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range t.header {
+			req.Header[key] = values
+		}
+
+		next := EMPTY_STRING
+		if i+1 < len(uris) {
+			next = uris[i+1]
+		}
+
+		// Время ожидания ответа ограничивается для каждого сервера
+		// отдельно, чтобы задержка одного сервера не расходовала время
+		// запроса к следующему
+		reqCtx, cancel := t.requestContext(ctx)
+		req = req.WithContext(reqCtx)
+
+		resp, err := t.client.Do(req)
+		if err != nil {
+			cancel()
+
+			if ctx.Err() != nil {
+				return nil, err
+			}
+
+			if !failover(err, idempotent) {
+				// Сервер не ответил за отведенное время, но запрос мог
+				// быть выполнен, поэтому на другой сервер не отправляется
+				if reqCtx.Err() != nil {
+					t.endpoints.failure(uri, EMPTY_STRING, err)
+				}
+				return nil, err
+			}
+
+			t.endpoints.failure(uri, next, err)
+			if next == EMPTY_STRING {
+				return nil, err
+			}
+			continue
+		}
+
+		if idempotent && next != EMPTY_STRING && unavailableStatus(resp.StatusCode) {
+			resp.Body.Close()
+			cancel()
+			t.endpoints.failure(uri, next, fmt.Errorf("server responded with status %d", resp.StatusCode))
+			continue
+		}
+
+		t.endpoints.success(uri)
+
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+		return resp, nil
+	}
+
+	return nil, ErrNoEndpoints
+}
+
+// requestContext function
+//
+// Контекст запроса к одному серверу, ограничение действует до закрытия
+// тела ответа.
+func (t *httpTransport) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.timeout > 0 {
+		return context.WithTimeout(ctx, t.timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// failover function
+//
+// Неидемпотентный запрос повторяется на другом сервере, только если
+// не удалось установить соединение и запрос точно не был отправлен.
+func failover(err error, idempotent bool) bool {
+	if idempotent {
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// unavailableStatus function
+func unavailableStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// servicesURL function
+//
+// Адрес списка сервисов API v1 на том же сервере, что и адрес
+// конфигурации uri, например http://host:8080/config.
+func servicesURL(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return strings.TrimSuffix(uri, CONFIG_PATH) + SERVICES_PATH
+	}
+
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), CONFIG_PATH) + SERVICES_PATH
+	u.RawPath = EMPTY_STRING
+	u.RawQuery = EMPTY_STRING
+
+	return u.String()
+}
+
+// makeGetOrDeleteRequest function
+func makeGetOrDeleteRequest(ctx context.Context, method string, uri string, service string, version int) (*http.Request, error) {
+	serviceUri := fmt.Sprintf("%s?service=%s&version=%d", uri, url.QueryEscape(service), version)
+	return http.NewRequestWithContext(ctx, method, serviceUri, nil)
+}
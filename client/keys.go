@@ -0,0 +1,179 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Разделитель ключей в пути к значению конфигурации
+const KEY_PATH_SEPARATOR = "."
+
+// KeyChangeFunc type
+//
+// Функция обработки изменения значения по пути в конфигурации. Значение
+// nil означает, что ключа нет в предыдущей или новой версии конфигурации.
+type KeyChangeFunc func(old json.RawMessage, new json.RawMessage)
+
+// keySubscription struct
+type keySubscription struct {
+	path []string
+	fn   KeyChangeFunc
+}
+
+// OnKeyChange function
+//
+// Вызывает fn, если в новой версии конфигурации изменилось значение
+// по пути path, например "db.pool_size" или "servers.0.host". Запускает
+// автоматическое обновление конфигурации с периодом WithRefreshPeriod,
+// если оно еще не запущено, первая полученная им версия считается
+// исходной. Возвращает функцию отмены подписки.
+func (c *ConfigClient) OnKeyChange(path string, fn KeyChangeFunc) func() {
+	sub := &keySubscription{
+		path: strings.Split(path, KEY_PATH_SEPARATOR),
+		fn:   fn,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Список подписок не изменяется, а заменяется, так как
+	// цикл обновления использует его без блокировки
+	c.keys = append(append([]*keySubscription{}, c.keys...), sub)
+	c.startRefresh(c.conn.opts.period)
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		keys := make([]*keySubscription, 0, len(c.keys))
+		for _, k := range c.keys {
+			if k != sub {
+				keys = append(keys, k)
+			}
+		}
+		c.keys = keys
+	}
+}
+
+// OnKey function
+//
+// Вызывает fn с декодированными значениями типа V, если в новой версии
+// конфигурации изменилось значение по пути path. Для отсутствующего
+// ключа передается нулевое значение, ошибки декодирования передаются
+// обработчику ошибок автоматического обновления.
+func OnKey[V any](c *ConfigClient, path string, fn func(old V, new V)) func() {
+	decode := func(data json.RawMessage, value *V) bool {
+		if data == nil {
+			return true
+		}
+
+		if err := json.Unmarshal(data, value); err != nil {
+			service, _ := c.serviceParams()
+			c.refreshError(&DecodeError{Service: service, Err: err})
+			return false
+		}
+
+		return true
+	}
+
+	return c.OnKeyChange(path, func(oldRaw json.RawMessage, newRaw json.RawMessage) {
+		var oldValue, newValue V
+
+		if decode(oldRaw, &oldValue) && decode(newRaw, &newValue) {
+			fn(oldValue, newValue)
+		}
+	})
+}
+
+// notifyKeys function
+//
+// Сравнивает значения по путям подписок в предыдущей и новой версиях
+// конфигурации. Конфигурации декодируются один раз для всех подписок.
+func (c *ConfigClient) notifyKeys(keys []*keySubscription, prev []byte, next []byte) {
+	oldDoc, err := decodeDocument(prev)
+	if err != nil {
+		c.refreshError(err)
+		return
+	}
+
+	newDoc, err := decodeDocument(next)
+	if err != nil {
+		c.refreshError(err)
+		return
+	}
+
+	for _, k := range keys {
+		oldValue, oldOk := lookupPath(oldDoc, k.path)
+		newValue, newOk := lookupPath(newDoc, k.path)
+
+		if oldOk == newOk && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		k.fn(encodeValue(oldValue, oldOk), encodeValue(newValue, newOk))
+	}
+}
+
+// decodeDocument function
+//
+// Числа декодируются как json.Number, чтобы сравнение не зависело
+// от точности float64.
+func decodeDocument(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var doc interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// lookupPath function
+func lookupPath(doc interface{}, path []string) (interface{}, bool) {
+	value := doc
+
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// encodeValue function
+func encodeValue(value interface{}, ok bool) json.RawMessage {
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifyKeys(t *testing.T) {
+	c := &ConfigClient{conn: newConn(nil, defaultOptions())}
+
+	var mu sync.Mutex
+	changes := map[string][2]string{}
+
+	subscribe := func(path string) *keySubscription {
+		return &keySubscription{
+			path: strings.Split(path, KEY_PATH_SEPARATOR),
+			fn: func(old json.RawMessage, new json.RawMessage) {
+				mu.Lock()
+				changes[path] = [2]string{string(old), string(new)}
+				mu.Unlock()
+			},
+		}
+	}
+
+	keys := []*keySubscription{
+		subscribe("db.pool_size"),
+		subscribe("db.host"),
+		subscribe("servers.1"),
+		subscribe("removed"),
+		subscribe("added"),
+		subscribe("big"),
+	}
+
+	prev := []byte(`{"db":{"pool_size":10,"host":"a"},"servers":["x","y"],"removed":true,"big":12345678901234567890}`)
+	next := []byte(`{"db":{"pool_size":20,"host":"a"},"servers":["x","z"],"added":1,"big":12345678901234567891}`)
+
+	c.notifyKeys(keys, prev, next)
+
+	want := map[string][2]string{
+		"db.pool_size": {"10", "20"},
+		"servers.1":    {`"y"`, `"z"`},
+		"removed":      {"true", ""},
+		"added":        {"", "1"},
+		"big":          {"12345678901234567890", "12345678901234567891"},
+	}
+
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	for path, values := range want {
+		if changes[path] != values {
+			t.Fatalf("change of %s = %v, want %v", path, changes[path], values)
+		}
+	}
+}
+
+func TestOnKeyChangeSkipsFirstVersion(t *testing.T) {
+	versions := []string{
+		`{"a":1,"b":1}`,
+		`{"a":2,"b":1}`,
+	}
+
+	var reads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(reads.Add(1)) - 1
+		if i >= len(versions) {
+			i = len(versions) - 1
+		}
+
+		w.Header().Set(VERSION_HEADER, "1")
+		w.Write([]byte(versions[i]))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+CONFIG_PATH, WithService("svc"), WithRefreshPeriod(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var mu sync.Mutex
+	var changes []string
+
+	for _, path := range []string{"a", "b"} {
+		path := path
+		OnKey[int](c, path, func(old int, new int) {
+			mu.Lock()
+			changes = append(changes, fmt.Sprintf("%s:%d->%d", path, old, new))
+			mu.Unlock()
+		})
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for reads.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(changes) != 1 || changes[0] != "a:1->2" {
+		t.Fatalf("changes = %v, want [a:1->2]", changes)
+	}
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Live struct
+//
+// Текущее значение конфигурации, которое обновляется автоматически.
+// Чтение значения не требует блокировок и функций обратного вызова.
+type Live[T any] struct {
+	value       atomic.Pointer[T]
+	unsubscribe func()
+}
+
+// Live function
+//
+// Читает конфигурацию и запускает ее автоматическое обновление
+// с периодом и обработчиком ошибок декодирования обертки Typed.
+// Live подписывается на обновления отдельно от OnChange, поэтому
+// для одного клиента можно создать несколько значений Live.
+func (t *Typed[T]) Live(ctx context.Context) (*Live[T], error) {
+	l := &Live[T]{}
+
+	value, err := t.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.value.Store(&value)
+
+	t.mu.Lock()
+	period := t.period
+	t.mu.Unlock()
+
+	l.unsubscribe = t.client.subscribe(period, func(data []byte) {
+		value, err := t.decode(data)
+		if err != nil {
+			t.decodeError(err)
+			return
+		}
+
+		l.value.Store(&value)
+	})
+
+	return l, nil
+}
+
+// Load function
+func (l *Live[T]) Load() T {
+	return *l.value.Load()
+}
+
+// Stop function
+//
+// Прекращает обновление значения. Автоматическое обновление клиента
+// для других подписок и функции OnChange продолжается.
+func (l *Live[T]) Stop() {
+	l.unsubscribe()
+}
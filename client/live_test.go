@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// liveConfig struct
+type liveConfig struct {
+	N int `json:"n"`
+}
+
+func TestLiveStopKeepsOtherSubscriptions(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(VERSION_HEADER, "1")
+		fmt.Fprintf(w, `{"n":%d}`, version.Load())
+	}))
+	defer srv.Close()
+
+	c, err := Connect(srv.URL+CONFIG_PATH, "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	typed := NewTyped[liveConfig](c)
+	typed.SetRefreshPeriod(10 * time.Millisecond)
+
+	ctx := context.Background()
+
+	first, err := typed.Live(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := typed.Live(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Stop()
+
+	var changes atomic.Int32
+	if err := typed.OnChange(func(old liveConfig, new liveConfig) { changes.Add(1) }); err != nil {
+		t.Fatal(err)
+	}
+
+	first.Stop()
+	version.Store(2)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for (second.Load().N != 2 || changes.Load() == 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if second.Load().N != 2 || changes.Load() == 0 {
+		t.Fatalf("updates stopped with the first Live: second = %+v, changes = %d", second.Load(), changes.Load())
+	}
+
+	if first.Load().N != 1 {
+		t.Fatalf("stopped Live is updated: %+v", first.Load())
+	}
+}
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Время ожидания ответа сервера на одну попытку запроса по умолчанию
+const DEFAULT_TIMEOUT = 5 * time.Second
+
+// Logger interface
+//
+// Журнал ошибок клиента, совместим с *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option type
+//
+// Функциональный параметр подключения к серверу конфигураций.
+type Option func(*options)
+
+// options struct
+type options struct {
+	service string
+	version int
+	retry   RetryPolicy
+	breaker BreakerSettings
+	hooks   Hooks
+	logger  Logger
+	period  time.Duration
+
+	cacheDir  string
+	endpoints []string
+
+	httpClient *http.Client
+	timeout    time.Duration
+	token      string
+	userAgent  string
+	header     http.Header
+}
+
+// Hooks struct
+//
+// Функции, вызываемые клиентом при ошибках запросов к серверу.
+// Вызываются синхронно, поэтому не должны блокироваться надолго.
+type Hooks struct {
+	// Перед повторной попыткой запроса, attempt – номер следующей попытки
+	OnRetry func(op string, attempt int, delay time.Duration, err error)
+
+	// Запрос завершился ошибкой после всех попыток
+	OnFailure func(op string, err error)
+
+	// Изменилось состояние автоматического выключателя
+	OnBreakerStateChange func(from BreakerState, to BreakerState)
+
+	// Ошибка автоматического обновления конфигурации
+	// (по умолчанию ошибки пишутся в журнал)
+	OnRefreshError func(err error)
+
+	// Сервер недоступен, возвращена локальная копия конфигурации
+	// с номером версии version, полученная от сервера в fetchedAt
+	OnStale func(version int, fetchedAt time.Time, err error)
+
+	// Ошибка чтения или записи локальной копии конфигурации
+	// (по умолчанию ошибки пишутся в журнал)
+	OnCacheError func(err error)
+
+	// Запрос переключен на другой сервер из-за ошибки сервера from
+	OnFailover func(from string, to string, err error)
+}
+
+// defaultOptions function
+func defaultOptions() *options {
+	return &options{
+		retry:   DefaultRetryPolicy(),
+		breaker: DefaultBreakerSettings(),
+		logger:  log.Default(),
+		timeout: DEFAULT_TIMEOUT,
+		period:  DEFAULT_REFRESH_PERIOD,
+		header:  http.Header{},
+	}
+}
+
+// applyOptions function
+func applyOptions(opts []Option) *options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// headers function
+//
+// Заголовки, добавляемые к каждому запросу к серверу.
+func (o *options) headers() http.Header {
+	header := o.header.Clone()
+
+	if o.userAgent != EMPTY_STRING {
+		header.Set("User-Agent", o.userAgent)
+	}
+
+	if o.token != EMPTY_STRING {
+		header.Set("Authorization", "Bearer "+o.token)
+	}
+
+	return header
+}
+
+// WithService function
+//
+// Имя сервиса, конфигурацией которого управляет клиент.
+func WithService(service string) Option {
+	return func(o *options) {
+		o.service = service
+	}
+}
+
+// WithVersion function
+//
+// Номер версии конфигурации, по умолчанию используется последняя версия.
+func WithVersion(version int) Option {
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// WithRetryPolicy function
+//
+// Политика повторных попыток запросов. Нулевое значение RetryPolicy
+// отключает повторные попытки.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithCircuitBreaker function
+//
+// Параметры автоматического выключателя. Нулевое значение
+// BreakerSettings отключает выключатель.
+func WithCircuitBreaker(settings BreakerSettings) Option {
+	return func(o *options) {
+		o.breaker = settings
+	}
+}
+
+// WithHooks function
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// WithCache function
+//
+// Каталог для локальной копии последней полученной конфигурации.
+// Если сервер недоступен, чтение конфигурации возвращает локальную копию.
+func WithCache(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
+// WithEndpoints function
+//
+// Дополнительные адреса серверов конфигураций для переключения при
+// недоступности основного. Адрес со схемой http+srv или https+srv
+// задает имя записей DNS SRV, например http+srv://_config._tcp.example.com/config.
+func WithEndpoints(uris ...string) Option {
+	return func(o *options) {
+		o.endpoints = append(o.endpoints, uris...)
+	}
+}
+
+// WithHTTPClient function
+//
+// HTTP клиент для запросов к серверу, например с собственным транспортом
+// для mTLS или трассировки. По умолчанию используется клиент с транспортом
+// OpenTelemetry.
+func WithHTTPClient(cl *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = cl
+	}
+}
+
+// WithTimeout function
+//
+// Время ожидания ответа сервера на одну попытку запроса, 0 отключает
+// ограничение. При переключении между серверами HTTP ограничение
+// действует для каждого сервера отдельно. Не применяется к потоку Watch.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithLogger function
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithBearerToken function
+//
+// Токен доступа, передается в заголовке Authorization.
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithUserAgent function
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithHeader function
+//
+// Заголовок, добавляемый к каждому запросу (для gRPC – метаданные).
+func WithHeader(key string, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithRefreshPeriod function
+//
+// Период автоматического обновления конфигурации, запускаемого
+// функцией OnKeyChange (по умолчанию 5 секунд).
+func WithRefreshPeriod(period time.Duration) Option {
+	return func(o *options) {
+		o.period = period
+	}
+}
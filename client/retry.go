@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy struct
+//
+// Повторные попытки запросов с экспоненциальной задержкой. Запись
+// конфигурации создает новую версию, поэтому по умолчанию повторяются
+// только чтение и удаление.
+type RetryPolicy struct {
+	// Максимальное число попыток, включая первую
+	MaxAttempts int
+
+	// Задержка перед первой повторной попыткой и ее верхняя граница
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Множитель задержки для каждой следующей попытки
+	Multiplier float64
+
+	// Доля случайного отклонения задержки, от 0 до 1
+	Jitter float64
+
+	// Повторять запросы на создание и обновление конфигурации
+	RetryWrites bool
+}
+
+// DefaultRetryPolicy function
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Stats struct
+//
+// Счетчики запросов клиента к серверу конфигураций.
+type Stats struct {
+	Requests     uint64
+	Failures     uint64
+	Retries      uint64
+	Rejected     uint64
+	BreakerState BreakerState
+}
+
+// clientStats struct
+type clientStats struct {
+	requests atomic.Uint64
+	failures atomic.Uint64
+	retries  atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// Генератор случайных отклонений задержки, общий для всех клиентов
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff function
+//
+// Задержка перед попыткой с номером attempt (начиная со второй).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 2; i < attempt; i++ {
+		delay *= p.Multiplier
+	}
+
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		jitterMu.Lock()
+		delay *= 1 + p.Jitter*(2*jitterRand.Float64()-1)
+		jitterMu.Unlock()
+	}
+
+	return time.Duration(delay)
+}
+
+// Stats function
+func (conn *Conn) Stats() Stats {
+	return Stats{
+		Requests:     conn.stats.requests.Load(),
+		Failures:     conn.stats.failures.Load(),
+		Retries:      conn.stats.retries.Load(),
+		Rejected:     conn.stats.rejected.Load(),
+		BreakerState: conn.breaker.current(),
+	}
+}
+
+// do function
+//
+// Выполняет запрос к серверу с учетом политики повторных попыток
+// и состояния автоматического выключателя.
+func (conn *Conn) do(ctx context.Context, op string, idempotent bool, fn func(ctx context.Context) error) error {
+	policy := conn.opts.retry
+
+	attempts := 1
+	if idempotent || policy.RetryWrites {
+		attempts = policy.MaxAttempts
+	}
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = conn.breaker.allow(); err != nil {
+			conn.stats.rejected.Add(1)
+			break
+		}
+
+		conn.stats.requests.Add(1)
+		err = fn(ctx)
+
+		// Отмена запроса вызывающей стороной ничего не говорит
+		// о доступности сервера
+		if err != nil && ctx.Err() != nil {
+			conn.breaker.cancel()
+		} else {
+			conn.breaker.done(unavailable(err))
+		}
+
+		if err == nil {
+			return nil
+		}
+		conn.stats.failures.Add(1)
+
+		if attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := policy.backoff(attempt + 1)
+
+		// Задержка не меньше указанной сервером в заголовке Retry-After
+		var e *Error
+		if errors.As(err, &e) && e.RetryAfter > delay {
+			delay = e.RetryAfter
+		}
+
+		if hook := conn.opts.hooks.OnRetry; hook != nil {
+			hook(op, attempt+1, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			conn.fail(op, err)
+			return err
+		case <-timer.C:
+		}
+
+		conn.stats.retries.Add(1)
+	}
+
+	conn.fail(op, err)
+	return err
+}
+
+// fail function
+func (conn *Conn) fail(op string, err error) {
+	if hook := conn.opts.hooks.OnFailure; hook != nil {
+		hook(op, err)
+	}
+}
+
+// unavailable function
+//
+// Ошибка означает, что сервер недоступен: ошибка сети
+// или ответ сервера с кодом 5xx.
+func unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode >= http.StatusInternalServerError && e.StatusCode != http.StatusNotImplemented
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryable function
+//
+// Запрос можно повторить, если сервер недоступен или ограничил
+// частоту запросов.
+func retryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) && e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	return unavailable(err)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffBounds(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{2, 100 * time.Millisecond},
+		{3, 200 * time.Millisecond},
+		{4, 400 * time.Millisecond},
+		{5, 800 * time.Millisecond},
+		{6, time.Second},
+		{10, time.Second},
+	}
+
+	for _, tt := range tests {
+		low := time.Duration(float64(tt.base) * (1 - p.Jitter))
+		high := time.Duration(float64(tt.base) * (1 + p.Jitter))
+
+		for i := 0; i < 100; i++ {
+			if delay := p.backoff(tt.attempt); delay < low || delay > high {
+				t.Fatalf("backoff(%d) = %s, want [%s, %s]", tt.attempt, delay, low, high)
+			}
+		}
+	}
+}
+
+func TestBackoffWithoutJitter(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 50 * time.Millisecond, Multiplier: 3}
+
+	if delay := p.backoff(4); delay != 450*time.Millisecond {
+		t.Fatalf("backoff(4) = %s, want 450ms", delay)
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Период автоматического обновления конфигурации по умолчанию
+const DEFAULT_REFRESH_PERIOD = 5 * time.Second
+
+// ErrDecode проверяется с помощью errors.Is для ошибок декодирования
+var ErrDecode = errors.New("config decode failed")
+
+// DecodeError struct
+//
+// Ошибка декодирования конфигурации, полученной от сервера. В отличие
+// от *Error, запрос к серверу при этом выполнен успешно.
+type DecodeError struct {
+	Service string
+	Err     error
+}
+
+// Error function
+func (e *DecodeError) Error() string {
+	if e.Service == EMPTY_STRING {
+		return fmt.Sprintf("couldn't decode config: %v", e.Err)
+	}
+
+	return fmt.Sprintf("couldn't decode config of service %q: %v", e.Service, e.Err)
+}
+
+// Unwrap function
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is function
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// Typed struct
+//
+// Обертка над ConfigClient для работы с конфигурацией как со
+// значением типа T вместо JSON. Конфигурация декодируется функцией Bind.
+type Typed[T any] struct {
+	client *ConfigClient
+
+	mu      sync.Mutex
+	period  time.Duration
+	onError func(error)
+	current T
+}
+
+// NewTyped function
+func NewTyped[T any](c *ConfigClient) *Typed[T] {
+	return &Typed[T]{
+		client: c,
+		period: c.conn.opts.period,
+		onError: func(err error) {
+			c.conn.opts.logger.Printf("%v", err)
+		},
+	}
+}
+
+// Client function
+func (t *Typed[T]) Client() *ConfigClient {
+	return t.client
+}
+
+// SetRefreshPeriod function
+func (t *Typed[T]) SetRefreshPeriod(period time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.period = period
+}
+
+// OnDecodeError function
+//
+// Задает обработчик ошибок декодирования и проверки новых версий
+// конфигурации при автоматическом обновлении (по умолчанию ошибки
+// пишутся в журнал). Такие версии не передаются функции OnChange.
+func (t *Typed[T]) OnDecodeError(fn func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onError = fn
+}
+
+// Get function
+func (t *Typed[T]) Get(ctx context.Context) (T, error) {
+	var value T
+
+	data, err := t.client.ReadConfigBytes(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	if value, err = t.decode(data); err != nil {
+		return value, err
+	}
+
+	t.mu.Lock()
+	t.current = value
+	t.mu.Unlock()
+
+	return value, nil
+}
+
+// Create function
+func (t *Typed[T]) Create(ctx context.Context, value T) (*ConfigVersion, error) {
+	return t.client.CreateConfig(ctx, value)
+}
+
+// Update function
+func (t *Typed[T]) Update(ctx context.Context, value T) (*ConfigVersion, error) {
+	return t.client.UpdateConfig(ctx, value)
+}
+
+// OnChange function
+//
+// Вызывает fn для каждой новой версии конфигурации с предыдущим
+// и новым значением. Предыдущее значение – последнее полученное через
+// Get или OnChange, до первого получения – нулевое значение типа T.
+func (t *Typed[T]) OnChange(fn func(old T, new T)) error {
+	t.mu.Lock()
+	period := t.period
+	t.mu.Unlock()
+
+	return t.client.AssignRefreshCallback(period, func(data []byte) {
+		value, err := t.decode(data)
+		if err != nil {
+			t.decodeError(err)
+			return
+		}
+
+		t.mu.Lock()
+		old := t.current
+		t.current = value
+		t.mu.Unlock()
+
+		fn(old, value)
+	})
+}
+
+// decodeError function
+func (t *Typed[T]) decodeError(err error) {
+	t.mu.Lock()
+	onError := t.onError
+	t.mu.Unlock()
+
+	onError(err)
+}
+
+// decode function
+func (t *Typed[T]) decode(data []byte) (T, error) {
+	value, err := Bind[T](data)
+	if err == nil {
+		return value, nil
+	}
+
+	service, _ := t.client.serviceParams()
+
+	var decodeErr *DecodeError
+	var invalidErr *InvalidConfigError
+
+	switch {
+	case errors.As(err, &decodeErr):
+		decodeErr.Service = service
+	case errors.As(err, &invalidErr):
+		invalidErr.Service = service
+	}
+
+	return value, err
+}
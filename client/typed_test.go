@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// typedConfig struct
+type typedConfig struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+func TestTypedGet(t *testing.T) {
+	body := `{"name":"db","size":10}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(VERSION_HEADER, "1")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c, err := Connect(srv.URL+CONFIG_PATH, "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	typed := NewTyped[typedConfig](c)
+
+	cfg, err := typed.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "db" || cfg.Size != 10 {
+		t.Fatalf("config = %+v", cfg)
+	}
+
+	// Ошибка декодирования отличается от ошибок запросов к серверу
+	body = `{"name":"db","size":"ten"}`
+
+	_, err = typed.Get(context.Background())
+	if !errors.Is(err, ErrDecode) {
+		t.Fatalf("error %v is not ErrDecode", err)
+	}
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Service != "svc" {
+		t.Fatalf("error = %#v", err)
+	}
+
+	var reqErr *Error
+	if errors.As(err, &reqErr) {
+		t.Fatalf("decode error is reported as request error: %v", err)
+	}
+}
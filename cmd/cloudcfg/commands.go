@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-cloud-camp/client"
+	"go-cloud-camp/internal/jsondoc"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Период опроса сервера в команде watch
+const DEFAULT_WATCH_PERIOD = 5 * time.Second
+
+// Число попыток применить патч, если конфигурация изменилась
+// после чтения
+const PATCH_ATTEMPTS = 3
+
+// cmdContext struct
+//
+// Аргументы и флаги команды.
+type cmdContext struct {
+	args   []string
+	out    *output
+	flags  connFlags
+	client *client.Conn
+
+	version int
+	create  bool
+	file    string
+	period  time.Duration
+	prune   bool
+	dryRun  bool
+}
+
+// versionFlag function
+func versionFlag(fs *flag.FlagSet, c *cmdContext) {
+	fs.IntVar(&c.version, "v", 0, "config version, 0 for the latest version (for delete: all versions)")
+}
+
+// createFlag function
+func createFlag(fs *flag.FlagSet, c *cmdContext) {
+	fs.BoolVar(&c.create, "create", false, "fail if the config already exists")
+}
+
+// fileFlag function
+func fileFlag(fs *flag.FlagSet, c *cmdContext) {
+	fs.StringVar(&c.file, "f", EMPTY_STRING, "compare a JSON/YAML file (\"-\" for stdin) with the config version <from> or the latest")
+}
+
+// periodFlag function
+func periodFlag(fs *flag.FlagSet, c *cmdContext) {
+	fs.DurationVar(&c.period, "period", DEFAULT_WATCH_PERIOD, "polling period (for gRPC: reconnect delay)")
+}
+
+// syncFlags function
+func syncFlags(fs *flag.FlagSet, c *cmdContext) {
+	fs.BoolVar(&c.prune, "prune", false, "delete configs of services without a file in the directory")
+	fs.BoolVar(&c.dryRun, "dry-run", false, "print the plan without applying it")
+}
+
+// connect function
+//
+// Соединение с сервером закрывается после выполнения команды.
+func (c *cmdContext) connect() (*client.Conn, error) {
+	conn, err := c.flags.connect()
+	if err != nil {
+		return nil, err
+	}
+	c.client = conn
+
+	return conn, nil
+}
+
+// service function
+//
+// Клиент конфигурации сервиса из первого аргумента команды, nargs
+// задает допустимое число аргументов (минимальное и максимальное).
+func (c *cmdContext) service(nargs ...int) (*client.ConfigClient, error) {
+	if len(c.args) < nargs[0] || len(c.args) > nargs[len(nargs)-1] {
+		return nil, errUsage
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Service(c.args[0]), nil
+}
+
+// runGet function
+func runGet(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(1)
+	if err != nil {
+		return err
+	}
+
+	data, err := cl.Version(c.version).ReadConfigBytes(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.out.raw(data)
+}
+
+// runSet function
+//
+// Обновляет конфигурацию сервиса, а если ее нет – создает.
+func runSet(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(1, 2)
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(argument(c.args, 1))
+	if err != nil {
+		return err
+	}
+
+	var created *client.ConfigVersion
+	if c.create {
+		created, err = cl.CreateConfig(ctx, json.RawMessage(data))
+	} else {
+		created, err = write(ctx, cl, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.out.value(created)
+}
+
+// runPatch function
+func runPatch(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(1, 2)
+	if err != nil {
+		return err
+	}
+
+	patchData, err := readInput(argument(c.args, 1))
+	if err != nil {
+		return err
+	}
+
+	patch, err := jsondoc.Decode(patchData)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		current, version, err := readMarked(ctx, cl)
+		if err != nil {
+			return err
+		}
+
+		doc, err := jsondoc.Decode(current)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(mergePatch(doc, patch))
+		if err != nil {
+			return err
+		}
+
+		// Конфигурация могла измениться после чтения, патч применяется
+		// к новой версии повторно
+		created, err := cl.UpdateConfigIfMatch(ctx, json.RawMessage(data), version.ETag)
+		if errors.Is(err, client.ErrPreconditionFailed) && i+1 < PATCH_ATTEMPTS {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.out.value(created)
+	}
+}
+
+// readMarked function
+//
+// Читает конфигурацию с открытыми секретными значениями, которую можно
+// записать обратно без потери секретов.
+func readMarked(ctx context.Context, cl *client.ConfigClient) ([]byte, *client.ConfigVersion, error) {
+	data, version, err := cl.ReadMarkedConfig(ctx)
+	if errors.Is(err, client.ErrForbidden) {
+		return nil, nil, fmt.Errorf("%w: reading secret values requires a token with the read-secrets permission", err)
+	}
+
+	return data, version, err
+}
+
+// runDelete function
+func runDelete(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(1)
+	if err != nil {
+		return err
+	}
+
+	return cl.Version(c.version).DeleteConfig(ctx)
+}
+
+// runList function
+func runList(ctx context.Context, c *cmdContext) error {
+	if len(c.args) != 0 {
+		return errUsage
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	services, err := conn.ListServices(ctx)
+	if err != nil {
+		return err
+	}
+
+	if services == nil {
+		services = []string{}
+	}
+
+	return c.out.value(services)
+}
+
+// runHistory function
+func runHistory(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(1)
+	if err != nil {
+		return err
+	}
+
+	versions, err := cl.Versions(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.out.value(versions)
+}
+
+// runDiff function
+//
+// Сравнивает версии from и to (по умолчанию последнюю) или файл -f
+// с версией from (по умолчанию последней).
+func runDiff(ctx context.Context, c *cmdContext) error {
+	maxArgs := 3
+	if c.file != EMPTY_STRING {
+		maxArgs = 2
+	}
+
+	cl, err := c.service(1, maxArgs)
+	if err != nil {
+		return err
+	}
+
+	versions, err := versionArgs(c.args[1:])
+	if err != nil {
+		return err
+	}
+
+	if c.file == EMPTY_STRING && len(versions) == 0 {
+		return errUsage
+	}
+
+	for len(versions) < 2 {
+		versions = append(versions, 0)
+	}
+
+	from, err := cl.Version(versions[0]).ReadConfigBytes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var to []byte
+	if c.file != EMPTY_STRING {
+		to, err = readInput(c.file)
+	} else {
+		to, err = cl.Version(versions[1]).ReadConfigBytes(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return diff(c.out.w, from, to)
+}
+
+// runRollback function
+func runRollback(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(2)
+	if err != nil {
+		return err
+	}
+
+	versions, err := versionArgs(c.args[1:])
+	if err != nil {
+		return err
+	}
+
+	if versions[0] == 0 {
+		return fmt.Errorf("%w: version must be positive", errUsage)
+	}
+
+	data, _, err := readMarked(ctx, cl.Version(versions[0]))
+	if err != nil {
+		return err
+	}
+
+	created, err := cl.UpdateConfig(ctx, json.RawMessage(data))
+	if err != nil {
+		return err
+	}
+
+	return c.out.value(created)
+}
+
+// runWatch function
+func runWatch(ctx context.Context, c *cmdContext) error {
+	cl, err := c.service(1)
+	if err != nil {
+		return err
+	}
+
+	printed := false
+	err = cl.AssignRefreshCallback(c.period, func(data []byte) {
+		if printed {
+			c.out.separator()
+		}
+		printed = true
+
+		if err := c.out.raw(data); err != nil {
+			fmt.Fprintln(os.Stderr, "cloudcfg:", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// write function
+func write(ctx context.Context, cl *client.ConfigClient, data []byte) (*client.ConfigVersion, error) {
+	created, err := cl.UpdateConfig(ctx, json.RawMessage(data))
+	if errors.Is(err, client.ErrNotFound) {
+		return cl.CreateConfig(ctx, json.RawMessage(data))
+	}
+
+	return created, err
+}
+
+// versionArgs function
+func versionArgs(args []string) ([]int, error) {
+	versions := make([]int, 0, len(args))
+
+	for _, arg := range args {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%w: invalid version %q", errUsage, arg)
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// argument function
+func argument(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+
+	return EMPTY_STRING
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/jsondoc"
+	"go-cloud-camp/internal/secrets"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Форматы вывода
+const (
+	FORMAT_JSON = "json"
+	FORMAT_YAML = "yaml"
+)
+
+// output struct
+type output struct {
+	w      io.Writer
+	format string
+}
+
+// check function
+func (o *output) check() error {
+	if o.format != FORMAT_JSON && o.format != FORMAT_YAML {
+		return fmt.Errorf("unknown output format %q, use %s or %s", o.format, FORMAT_JSON, FORMAT_YAML)
+	}
+
+	return nil
+}
+
+// value function
+//
+// Выводит значение, которое кодируется в JSON.
+func (o *output) value(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return o.raw(data)
+}
+
+// raw function
+//
+// Выводит документ JSON. При выводе в YAML порядок ключей и запись
+// чисел сохраняются.
+func (o *output) raw(data []byte) error {
+	if o.format == FORMAT_JSON {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, EMPTY_STRING, "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+		_, err := o.w.Write(buf.Bytes())
+		return err
+	}
+
+	// JSON является подмножеством YAML, поэтому документ разбирается
+	// как YAML в дерево узлов, а затем выводится в блочном стиле
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(o.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// separator function
+//
+// Разделитель документов при выводе нескольких конфигураций.
+func (o *output) separator() {
+	if o.format == FORMAT_YAML {
+		fmt.Fprintln(o.w, "---")
+	}
+}
+
+// blockStyle function
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+// readInput function
+//
+// Читает документ JSON или YAML из файла path или из стандартного
+// ввода, если path не задан или равен "-". Возвращает документ JSON.
+func readInput(path string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if path == EMPTY_STRING || path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toJSON(data)
+}
+
+// toJSON function
+//
+// Документ JSON возвращается без изменений, документ YAML
+// преобразуется в JSON.
+func toJSON(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty config data")
+	}
+
+	return jsondoc.FromYAML(data)
+}
+
+// mergePatch function
+//
+// Применяет к документу target изменения patch по правилам
+// JSON Merge Patch (RFC 7396): значение null удаляет ключ,
+// объекты объединяются, остальные значения заменяются.
+// Секретное значение {"$secret": value} остается секретным, если
+// патч заменяет его обычным значением.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	if marker, inner, ok := secrets.Marker(target); ok && marker == secrets.SECRET_MARKER {
+		if _, _, ok := secrets.Marker(patch); !ok {
+			return map[string]interface{}{secrets.SECRET_MARKER: mergePatch(inner, patch)}
+		}
+	}
+
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+
+		t[key] = mergePatch(t[key], value)
+	}
+
+	return t
+}
+
+// diff function
+//
+// Построчное сравнение значений двух документов JSON: удаленные
+// значения выводятся со знаком "-", новые со знаком "+".
+func diff(w io.Writer, from []byte, to []byte) error {
+	fromDoc, err := jsondoc.Decode(from)
+	if err != nil {
+		return err
+	}
+
+	toDoc, err := jsondoc.Decode(to)
+	if err != nil {
+		return err
+	}
+
+	writeChanges(w, EMPTY_STRING, jsondoc.Diff(fromDoc, toDoc))
+
+	return nil
+}
+
+// writeChanges function
+func writeChanges(w io.Writer, indent string, changes []jsondoc.Change) {
+	for _, c := range changes {
+		path := c.Path
+		if path == EMPTY_STRING {
+			path = "(root)"
+		}
+
+		if c.Old != nil {
+			fmt.Fprintf(w, "%s- %s: %s\n", indent, path, c.Old)
+		}
+		if c.New != nil {
+			fmt.Fprintf(w, "%s+ %s: %s\n", indent, path, c.New)
+		}
+	}
+}
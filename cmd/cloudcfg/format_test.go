@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"go-cloud-camp/internal/jsondoc"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"replace", `{"a":1,"b":2}`, `{"a":3}`, `{"a":3,"b":2}`},
+		{"delete", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{"nested", `{"db":{"host":"h","port":1}}`, `{"db":{"port":2}}`, `{"db":{"host":"h","port":2}}`},
+		{"object replaces value", `{"a":1}`, `{"a":{"b":1}}`, `{"a":{"b":1}}`},
+		{"array replaces array", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"not object", `{"a":1}`, `[1]`, `[1]`},
+		{"secret kept", `{"db":{"password":{"$secret":"p"},"user":"u"}}`, `{"db":{"user":"v"}}`,
+			`{"db":{"password":{"$secret":"p"},"user":"v"}}`},
+		{"secret replaced", `{"password":{"$secret":"p"}}`, `{"password":"q"}`, `{"password":{"$secret":"q"}}`},
+		{"secret marker replaced", `{"password":{"$secret":"p"}}`, `{"password":{"$secret":"q"}}`, `{"password":{"$secret":"q"}}`},
+		{"secret object merged", `{"tls":{"$secret":{"cert":"c","key":"k"}}}`, `{"tls":{"key":"n"}}`,
+			`{"tls":{"$secret":{"cert":"c","key":"n"}}}`},
+		{"secret deleted", `{"password":{"$secret":"p"},"user":"u"}`, `{"password":null}`, `{"user":"u"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := jsondoc.Decode([]byte(tt.target))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			patch, err := jsondoc.Decode([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.Marshal(mergePatch(target, patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("mergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
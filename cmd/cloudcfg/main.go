@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+const EMPTY_STRING = ""
+
+// errUsage возвращается при неверных аргументах команды
+var errUsage = errors.New("invalid arguments")
+
+// command struct
+type command struct {
+	args  string
+	about string
+	run   func(ctx context.Context, cmd *cmdContext) error
+
+	// Собственные флаги команды
+	flags func(fs *flag.FlagSet, cmd *cmdContext)
+}
+
+// commands variable
+var commands = map[string]*command{
+	"get":      {"<service>", "print the config of a service", runGet, versionFlag},
+	"set":      {"<service> [file]", "write a config from a JSON/YAML file or stdin", runSet, createFlag},
+	"patch":    {"<service> [file]", "apply a JSON merge patch from a file or stdin", runPatch, nil},
+	"delete":   {"<service>", "delete a config version or all versions", runDelete, versionFlag},
+	"list":     {EMPTY_STRING, "list services", runList, nil},
+	"history":  {"<service>", "list config versions of a service", runHistory, nil},
+	"diff":     {"<service> <from> [to]", "compare two config versions, or a file with -f", runDiff, fileFlag},
+	"rollback": {"<service> <version>", "write an old config version as a new version", runRollback, nil},
+	"watch":    {"<service>", "print each new config version until interrupted", runWatch, periodFlag},
+	"sync":     {"<dir>", "make configs match the <service>.json|yaml files of a directory", runSync, syncFlags},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cloudcfg: unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := execute(ctx, name, cmd, os.Args[2:])
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cloudcfg:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// execute function
+func execute(ctx context.Context, name string, cmd *command, args []string) error {
+	c := &cmdContext{out: &output{w: os.Stdout}}
+
+	fs := flag.NewFlagSet("cloudcfg "+name, flag.ContinueOnError)
+	fs.StringVar(&c.out.format, "o", FORMAT_JSON, "output format: json or yaml")
+	c.flags.register(fs)
+	if cmd.flags != nil {
+		cmd.flags(fs, c)
+	}
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcfg %s %s [flags]\n\n%s\n\nFlags:\n", name, cmd.args, cmd.about)
+		fs.PrintDefaults()
+	}
+
+	var err error
+	if c.args, err = parseArgs(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+
+	if err := c.out.check(); err != nil {
+		return err
+	}
+
+	err = cmd.run(ctx, c)
+
+	if c.client != nil {
+		c.client.Close()
+	}
+
+	if errors.Is(err, errUsage) {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "cloudcfg:", err)
+		}
+		fs.Usage()
+	}
+
+	return err
+}
+
+// parseArgs function
+//
+// Флаги можно указывать как до, так и после аргументов команды.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// usage function
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Usage: cloudcfg <command> [arguments] [flags]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-9s %s\n", name, commands[name].about)
+	}
+	b.WriteString("\nServer URL and token are taken from flags -url and -token, env " +
+		ENV_URL + " and " + ENV_TOKEN + ", or a profile\nin the profile file (env " +
+		ENV_CONFIG + ", profile name in -profile or " + ENV_PROFILE + ").\n" +
+		"Run 'cloudcfg <command> -h' for command flags.\n")
+
+	fmt.Fprint(os.Stderr, b.String())
+}
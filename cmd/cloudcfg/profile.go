@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go-cloud-camp/client"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Переменные окружения с параметрами подключения к серверу
+const (
+	ENV_URL     = "CLOUDCFG_URL"
+	ENV_TOKEN   = "CLOUDCFG_TOKEN"
+	ENV_PROFILE = "CLOUDCFG_PROFILE"
+	ENV_CONFIG  = "CLOUDCFG_CONFIG"
+)
+
+const (
+	DEFAULT_URL     = "http://localhost:8080/config"
+	DEFAULT_PROFILE = "default"
+
+	// Адрес сервера gRPC задается в формате grpc://host:port
+	GRPC_SCHEME = "grpc://"
+
+	USER_AGENT = "cloudcfg"
+)
+
+// Profile struct
+type Profile struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
+// profileFile struct
+//
+// Файл профилей, по умолчанию ~/.config/cloudcfg/config.yml:
+//
+//	current: dev
+//	profiles:
+//	  dev:
+//	    url: http://localhost:8080/config
+//	  prod:
+//	    url: grpc://config.example.com:9090
+//	    token: secret
+type profileFile struct {
+	Current  string              `yaml:"current"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// connFlags struct
+//
+// Параметры подключения, общие для всех команд.
+type connFlags struct {
+	profile string
+	url     string
+	token   string
+}
+
+// register function
+func (f *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.profile, "profile", EMPTY_STRING, "profile name from the profile file (env "+ENV_PROFILE+")")
+	fs.StringVar(&f.url, "url", EMPTY_STRING, "server URL, grpc://host:port for gRPC (env "+ENV_URL+")")
+	fs.StringVar(&f.token, "token", EMPTY_STRING, "bearer token (env "+ENV_TOKEN+")")
+}
+
+// resolve function
+//
+// Параметры подключения берутся из флагов, затем из переменных
+// окружения, затем из профиля.
+func (f *connFlags) resolve() (*Profile, error) {
+	p, err := loadProfile(first(f.profile, os.Getenv(ENV_PROFILE)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Profile{
+		URL:   first(f.url, os.Getenv(ENV_URL), p.URL, DEFAULT_URL),
+		Token: first(f.token, os.Getenv(ENV_TOKEN), p.Token),
+	}, nil
+}
+
+// connect function
+func (f *connFlags) connect() (*client.Conn, error) {
+	p, err := f.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Option{client.WithUserAgent(USER_AGENT)}
+	if p.Token != EMPTY_STRING {
+		opts = append(opts, client.WithBearerToken(p.Token))
+	}
+
+	if strings.HasPrefix(p.URL, GRPC_SCHEME) {
+		return client.OpenGRPC(strings.TrimPrefix(p.URL, GRPC_SCHEME), opts...)
+	}
+
+	return client.Open(p.URL, opts...)
+}
+
+// profilePath function
+func profilePath() (string, error) {
+	if path := os.Getenv(ENV_CONFIG); path != EMPTY_STRING {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return EMPTY_STRING, err
+	}
+
+	return filepath.Join(dir, "cloudcfg", "config.yml"), nil
+}
+
+// loadProfile function
+//
+// Профиль name из файла профилей. Если имя не задано, используется
+// профиль current из файла или профиль default. Отсутствие файла или
+// профиля по умолчанию не считается ошибкой.
+func loadProfile(name string) (*Profile, error) {
+	path, err := profilePath()
+	if err != nil {
+		if name == EMPTY_STRING {
+			return &Profile{}, nil
+		}
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && name == EMPTY_STRING {
+			return &Profile{}, nil
+		}
+		return nil, err
+	}
+
+	file := &profileFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("invalid profile file %s: %w", path, err)
+	}
+
+	explicit := name != EMPTY_STRING
+	name = first(name, file.Current, DEFAULT_PROFILE)
+
+	p, ok := file.Profiles[name]
+	if !ok || p == nil {
+		if explicit || file.Current != EMPTY_STRING {
+			return nil, fmt.Errorf("profile %q not found in %s", name, path)
+		}
+		return &Profile{}, nil
+	}
+
+	return p, nil
+}
+
+// first function
+//
+// Первое непустое значение.
+func first(values ...string) string {
+	for _, v := range values {
+		if v != EMPTY_STRING {
+			return v
+		}
+	}
+
+	return EMPTY_STRING
+}
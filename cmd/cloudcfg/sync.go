@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/client"
+	"go-cloud-camp/internal/configsync"
+)
+
+// Отступ изменений значений в плане синхронизации
+const PLAN_INDENT = "    "
+
+// Знаки действий плана синхронизации
+var actionSigns = map[string]string{
+	configsync.ACTION_CREATE: "+",
+	configsync.ACTION_UPDATE: "~",
+	configsync.ACTION_DELETE: "-",
+}
+
+// runSync function
+//
+// Приводит конфигурации на сервере к файлам каталога и выводит
+// план изменений.
+func runSync(ctx context.Context, c *cmdContext) error {
+	if len(c.args) != 1 {
+		return errUsage
+	}
+
+	desired, err := configsync.LoadDir(c.args[0])
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	target := &clientTarget{conn: conn}
+
+	plan, err := configsync.MakePlan(ctx, target, desired, c.prune)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range plan.Changes {
+		fmt.Fprintf(c.out.w, "%s %s (%s)\n", actionSigns[change.Action], change.Service, change.Action)
+		writeChanges(c.out.w, PLAN_INDENT, change.Changes)
+	}
+	fmt.Fprintf(c.out.w, "Plan: %s.\n", plan.Summary())
+
+	if c.dryRun || len(plan.Changes) == 0 {
+		return nil
+	}
+
+	err = configsync.Apply(ctx, target, plan)
+
+	for _, change := range plan.Changes {
+		if change.Err != nil {
+			fmt.Fprintf(c.out.w, "%s: %s failed: %v\n", change.Service, change.Action, change.Err)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out.w, "Applied.")
+
+	return nil
+}
+
+// clientTarget struct
+type clientTarget struct {
+	conn *client.Conn
+}
+
+// Services function
+func (t *clientTarget) Services(ctx context.Context) ([]string, error) {
+	return t.conn.ListServices(ctx)
+}
+
+// Latest function
+//
+// Без разрешения read-secrets сервер скрывает значения секретов,
+// и план сравнивал бы файлы каталога со скрытыми значениями, поэтому
+// синхронизация завершается ошибкой.
+func (t *clientTarget) Latest(ctx context.Context, service string) (json.RawMessage, error) {
+	data, _, err := readMarked(ctx, t.conn.Service(service))
+	if errors.Is(err, client.ErrNotFound) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// Create function
+func (t *clientTarget) Create(ctx context.Context, service string, data json.RawMessage) error {
+	_, err := t.conn.Service(service).CreateConfig(ctx, data)
+	return err
+}
+
+// Update function
+func (t *clientTarget) Update(ctx context.Context, service string, data json.RawMessage) error {
+	_, err := t.conn.Service(service).UpdateConfig(ctx, data)
+	return err
+}
+
+// Delete function
+func (t *clientTarget) Delete(ctx context.Context, service string) error {
+	return t.conn.Service(service).DeleteConfig(ctx)
+}
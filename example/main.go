@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"go-cloud-camp/client"
 	"log"
@@ -15,7 +14,7 @@ type AppConfig struct {
 }
 
 func main() {
-	cfgClient, err := client.Connect("http://localhost:8080/config", "example")
+	cfgClient, err := client.New("http://localhost:8080/config", client.WithService("example"))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -26,37 +25,44 @@ func main() {
 
 	time.Sleep(1 * time.Second)
 
-	newCfg := &AppConfig{
+	appCfg := client.NewTyped[AppConfig](cfgClient)
+	appCfg.SetRefreshPeriod(2 * time.Second)
+
+	created, err := appCfg.Create(context.Background(), AppConfig{
 		Key1: "Value1",
 		Key2: "Value2",
-	}
-
-	if err = cfgClient.CreateConfig(context.Background(), newCfg); err != nil {
+	})
+	if err != nil {
 		log.Fatalln(err)
 	}
 
-	readedCfg := &AppConfig{}
+	fmt.Printf("Created config version: %d\n", created.Version)
 
-	if err = cfgClient.ReadAndDecodeConfig(context.Background(), readedCfg); err != nil {
+	readedCfg, err := appCfg.Get(context.Background())
+	if err != nil {
 		log.Fatalln(err)
 	}
 
 	fmt.Printf("Initial config: %#v\n", readedCfg)
 
-	if err = cfgClient.AssignRefreshCallback(2*time.Second, LogConfigRefresh); err != nil {
+	err = appCfg.OnChange(func(old AppConfig, new AppConfig) {
+		fmt.Printf("Updated config: %#v -> %#v\n", old, new)
+	})
+	if err != nil {
 		log.Println(err)
 	}
 
 	for i := 0; i < 5; i++ {
 		time.Sleep(5 * time.Second)
-		cfgUpdate := &AppConfig{
+		updated, err := appCfg.Update(context.Background(), AppConfig{
 			Key1: fmt.Sprintf("Value-%d", i*1000),
 			Key2: fmt.Sprintf("Value-%d", i*2000),
-		}
-		err := cfgClient.UpdateConfig(context.Background(), cfgUpdate)
+		})
 		if err != nil {
 			log.Println(err)
+			continue
 		}
+		fmt.Printf("Updated config version: %d\n", updated.Version)
 	}
 
 	time.Sleep(5 * time.Second)
@@ -65,15 +71,3 @@ func main() {
 	var ss string
 	fmt.Scanln(&ss)
 }
-
-func LogConfigRefresh(data []byte) {
-	newCfg := &AppConfig{}
-
-	err := json.Unmarshal(data, newCfg)
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	fmt.Printf("Updated config: %#v\n", newCfg)
-}
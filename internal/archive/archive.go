@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/common"
+	"io"
+	"time"
+)
+
+const (
+	// Формат архива: первая строка содержит заголовок, каждая следующая
+	// строка - одну версию конфига сервиса (NDJSON)
+	FORMAT         = "go-cloud-camp/export"
+	FORMAT_VERSION = 1
+
+	CONTENT_TYPE = "application/x-ndjson"
+)
+
+// Header struct
+type Header struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// entry struct
+//
+// Версия конфига в архиве. Данные сохраняются в том виде, в котором
+// они хранятся на сервере, секретные значения остаются зашифрованными.
+type entry struct {
+	common.VersionInfo
+	Data json.RawMessage `json:"data"`
+}
+
+// Writer struct
+type Writer struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewWriter function
+func NewWriter(w io.Writer) *Writer {
+	bw := bufio.NewWriter(w)
+
+	return &Writer{
+		w:       bw,
+		encoder: json.NewEncoder(bw),
+	}
+}
+
+// WriteHeader function
+func (aw *Writer) WriteHeader() error {
+	return aw.encoder.Encode(&Header{
+		Format:     FORMAT,
+		Version:    FORMAT_VERSION,
+		ExportedAt: time.Now().UTC(),
+	})
+}
+
+// Write function
+func (aw *Writer) Write(record *common.ConfigRecord) error {
+	return aw.encoder.Encode(&entry{VersionInfo: record.VersionInfo, Data: record.Data})
+}
+
+// Flush function
+func (aw *Writer) Flush() error {
+	return aw.w.Flush()
+}
+
+// ReadAll function
+//
+// Читает и проверяет архив целиком. Ошибки формата архива
+// соответствуют common.ErrInvalidArchive.
+func ReadAll(r io.Reader) ([]*common.ConfigRecord, error) {
+	decoder := json.NewDecoder(r)
+
+	header := &Header{}
+	if err := decoder.Decode(header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: empty archive", common.ErrInvalidArchive)
+		}
+		return nil, invalid("header", err)
+	}
+
+	if header.Format != FORMAT {
+		return nil, fmt.Errorf("%w: unknown format %q", common.ErrInvalidArchive, header.Format)
+	}
+	if header.Version < 1 || header.Version > FORMAT_VERSION {
+		return nil, fmt.Errorf("%w: unsupported format version %d", common.ErrInvalidArchive, header.Version)
+	}
+
+	var records []*common.ConfigRecord
+	seen := map[string]map[int]bool{}
+
+	for n := 1; ; n++ {
+		e := &entry{}
+		if err := decoder.Decode(e); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, invalid(fmt.Sprintf("record %d", n), err)
+		}
+
+		if err := validate(e, seen); err != nil {
+			return nil, invalid(fmt.Sprintf("record %d", n), err)
+		}
+
+		records = append(records, &common.ConfigRecord{VersionInfo: e.VersionInfo, Data: e.Data})
+	}
+
+	return records, nil
+}
+
+// validate function
+func validate(e *entry, seen map[string]map[int]bool) error {
+	if e.Service == common.EMPTY_STRING {
+		return common.ErrEmptyServiceName
+	}
+
+	if e.Version < 1 {
+		return common.ErrInvalidVersion
+	}
+
+	if len(e.Data) == 0 || string(e.Data) == "null" {
+		return common.ErrNotValidJsonData
+	}
+
+	if seen[e.Service] == nil {
+		seen[e.Service] = map[int]bool{}
+	}
+	if seen[e.Service][e.Version] {
+		return fmt.Errorf("duplicate version %d of service %q", e.Version, e.Service)
+	}
+	seen[e.Service][e.Version] = true
+
+	return nil
+}
+
+// invalid function
+func invalid(where string, err error) error {
+	return fmt.Errorf("%w: %s: %v", common.ErrInvalidArchive, where, err)
+}
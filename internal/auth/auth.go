@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/config"
+	"net/http"
+	"strings"
+)
+
+// Permissions
+const (
+	PERMISSION_READ_SECRETS = "read-secrets"
+
+	// Выгрузка и загрузка всех конфигураций через маршруты /admin
+	PERMISSION_ADMIN = "admin"
+)
+
+const (
+	ANONYMOUS     = "anonymous"
+	BEARER_SCHEME = "Bearer "
+)
+
+// Identity struct
+type Identity struct {
+	Name        string
+	Permissions map[string]bool
+}
+
+// Has function
+func (i *Identity) Has(permission string) bool {
+	return i != nil && i.Permissions[permission]
+}
+
+// Authenticator struct
+type Authenticator struct {
+	anonymous *Identity
+	tokens    map[[sha256.Size]byte]*Identity
+}
+
+type contextKey struct{}
+
+// Create function
+func Create(cfg *config.AuthParams) *Authenticator {
+	a := &Authenticator{
+		anonymous: newIdentity(ANONYMOUS, cfg.AnonymousPermissions),
+		tokens:    make(map[[sha256.Size]byte]*Identity, len(cfg.Tokens)),
+	}
+
+	// Храним только хеши токенов
+	for _, t := range cfg.Tokens {
+		a.tokens[sha256.Sum256([]byte(t.Token))] = newIdentity(t.Name, t.Permissions)
+	}
+
+	return a
+}
+
+// Identify function
+func (a *Authenticator) Identify(r *http.Request) (*Identity, error) {
+	return a.IdentifyHeader(r.Header.Get("Authorization"))
+}
+
+// IdentifyHeader function
+//
+// Определяет клиента по значению заголовка Authorization
+// (или метаданных authorization запроса gRPC).
+func (a *Authenticator) IdentifyHeader(header string) (*Identity, error) {
+	if header == common.EMPTY_STRING {
+		return a.anonymous, nil
+	}
+
+	if !strings.HasPrefix(header, BEARER_SCHEME) {
+		return nil, common.ErrUnauthorized
+	}
+
+	identity, ok := a.tokens[sha256.Sum256([]byte(strings.TrimPrefix(header, BEARER_SCHEME)))]
+	if !ok {
+		return nil, common.ErrUnauthorized
+	}
+
+	return identity, nil
+}
+
+// WithIdentity function
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext function
+func FromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(contextKey{}).(*Identity)
+	return identity
+}
+
+// newIdentity function
+func newIdentity(name string, permissions []string) *Identity {
+	identity := &Identity{
+		Name:        name,
+		Permissions: make(map[string]bool, len(permissions)),
+	}
+
+	for _, p := range permissions {
+		identity.Permissions[p] = true
+	}
+
+	return identity
+}
@@ -0,0 +1,56 @@
+package common
+
+import "errors"
+
+// Машиночитаемые коды ошибок, возвращаемые клиентам сервера
+const (
+	CODE_NOT_FOUND          = "not_found"
+	CODE_SERVICE_NOT_FOUND  = "service_not_found"
+	CODE_ALREADY_EXISTS     = "already_exists"
+	CODE_CONFIG_IN_USE      = "config_in_use"
+	CODE_EMPTY_SERVICE_NAME = "empty_service_name"
+	CODE_INVALID_JSON       = "invalid_json"
+	CODE_INVALID_REQUEST    = "invalid_request"
+	CODE_INVALID_VERSION    = "invalid_version"
+	CODE_SECRETS_DISABLED   = "secrets_disabled"
+	CODE_INVALID_ARCHIVE    = "invalid_archive"
+	CODE_FORBIDDEN          = "forbidden"
+	CODE_BODY_TOO_LARGE     = "body_too_large"
+	CODE_UNAUTHORIZED       = "unauthorized"
+	CODE_RATE_LIMITED       = "rate_limited"
+	CODE_PRECONDITION       = "precondition_failed"
+	CODE_INTERNAL           = "internal_error"
+)
+
+// errorCodes variable
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrNotFound, CODE_NOT_FOUND},
+	{ErrServiceNotFound, CODE_SERVICE_NOT_FOUND},
+	{ErrAlreadyCreated, CODE_ALREADY_EXISTS},
+	{ErrConfigIsUsed, CODE_CONFIG_IN_USE},
+	{ErrEmptyServiceName, CODE_EMPTY_SERVICE_NAME},
+	{ErrNotValidJsonData, CODE_INVALID_JSON},
+	{ErrInvalidRequest, CODE_INVALID_REQUEST},
+	{ErrInvalidVersion, CODE_INVALID_VERSION},
+	{ErrSecretsDisabled, CODE_SECRETS_DISABLED},
+	{ErrInvalidArchive, CODE_INVALID_ARCHIVE},
+	{ErrForbidden, CODE_FORBIDDEN},
+	{ErrBodyTooLarge, CODE_BODY_TOO_LARGE},
+	{ErrUnauthorized, CODE_UNAUTHORIZED},
+	{ErrRateLimited, CODE_RATE_LIMITED},
+	{ErrPreconditionFailed, CODE_PRECONDITION},
+}
+
+// ErrorCode function
+func ErrorCode(err error) string {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+
+	return CODE_INTERNAL
+}
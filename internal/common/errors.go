@@ -9,3 +9,44 @@ var ErrEmptyServiceName = errors.New("empty service name")
 var ErrNotValidJsonData = errors.New("not valid json data")
 var ErrServiceNotFound = errors.New("service not found")
 var ErrConfigIsUsed = errors.New("config is used")
+var ErrSecretsDisabled = errors.New("secrets encryption is not configured")
+var ErrUnauthorized = errors.New("unauthorized")
+var ErrRateLimited = errors.New("rate limit exceeded")
+var ErrInvalidRequest = errors.New("invalid request")
+var ErrBodyTooLarge = errors.New("request body too large")
+var ErrInvalidVersion = errors.New("invalid config version")
+var ErrForbidden = errors.New("permission denied")
+var ErrInvalidArchive = errors.New("invalid archive")
+var ErrPreconditionFailed = errors.New("config was modified")
+
+// InvalidParam struct
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError struct
+//
+// Ошибка проверки параметров запроса с описанием неверных параметров.
+type ValidationError struct {
+	Err    error
+	Params []InvalidParam
+}
+
+// Error function
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap function
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// NewValidationError function
+func NewValidationError(err error, name string, reason string) *ValidationError {
+	return &ValidationError{
+		Err:    err,
+		Params: []InvalidParam{{Name: name, Reason: reason}},
+	}
+}
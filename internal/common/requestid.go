@@ -0,0 +1,43 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const MAX_REQUEST_ID_LENGTH = 128
+
+// RequestID function
+//
+// Возвращает переданный клиентом идентификатор запроса,
+// или новый случайный идентификатор, если переданный не подходит.
+func RequestID(id string) string {
+	if isValidRequestID(id) {
+		return id
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// isValidRequestID function
+func isValidRequestID(id string) bool {
+	if id == EMPTY_STRING || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+
+	// Допускаются только печатные ASCII символы без пробелов
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
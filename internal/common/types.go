@@ -1,13 +1,92 @@
 package common
 
-import "encoding/json"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 const (
 	EMPTY_STRING = ""
+
+	// Заголовок запросов автоматического обновления конфига клиентом
+	REFRESH_HEADER = "X-Config-Refresh"
+
+	// Заголовок ответа с номером версии конфига
+	VERSION_HEADER = "X-Config-Version"
+
+	// Параметр чтения конфига и его значение, при котором секретные
+	// значения возвращаются открытыми в маркерах {"$secret": value}
+	SECRETS_PARAM  = "secrets"
+	SECRETS_MARKED = "marked"
 )
 
 // RequestData struct
 type RequestData struct {
 	Service string          `json:"service"`
 	Data    json.RawMessage `json:"data"`
+
+	// ETag последней версии конфига, от которой создается новая
+	// версия (заголовок If-Match), пустое значение отключает проверку.
+	// ETag слабый и сравнивается как строка целиком, в отличие от
+	// строгого сравнения If-Match по RFC 7232
+	IfMatch string `json:"-"`
+}
+
+// RewriteFunc type
+//
+// Функция преобразования данных конфига, возвращает новые данные
+// и признак того, что данные были изменены.
+type RewriteFunc func(data json.RawMessage) (json.RawMessage, bool, error)
+
+// VersionInfo struct
+type VersionInfo struct {
+	Service   string    `json:"service"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	ETag      string    `json:"etag"`
+}
+
+// ConfigRecord struct
+type ConfigRecord struct {
+	VersionInfo
+	Data json.RawMessage
+}
+
+// ETag function
+//
+// Слабый ETag версии конфига, вычисляется по данным при сохранении
+// версии и не зависит от маскирования секретов в ответе. Хранилище
+// сохраняет ETag вместе с версией, поэтому перешифровка секретов
+// при ротации ключей его не меняет.
+func ETag(service string, version int, data json.RawMessage) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%d\x00", service, version)
+	hash.Write(data)
+
+	return fmt.Sprintf(`W/"%s"`, hex.EncodeToString(hash.Sum(nil)[:16]))
+}
+
+// ImportParams struct
+//
+// Параметры загрузки архива конфигураций.
+type ImportParams struct {
+	Replace          bool
+	PreserveVersions bool
+
+	// Разрешает при Replace загрузку архива без версий,
+	// которая удаляет все сервисы
+	AllowEmpty bool
+}
+
+// ImportResult struct
+//
+// Итог загрузки архива конфигураций.
+type ImportResult struct {
+	Services []string `json:"services"`
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+	Deleted  []string `json:"deleted"`
 }
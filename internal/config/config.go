@@ -20,6 +20,7 @@ type ListenParams struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout" env-default:"5s"`
 	WriteTimeout    time.Duration `yaml:"write_timeout" env-default:"5s"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"5s"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env-default:"0s"`
 }
 
 // StorageParams struct
@@ -38,11 +39,86 @@ type MongodbParams struct {
 	Database    string `yaml:"database" env-default:"configs"`
 }
 
+// SecretsParams struct
+type SecretsParams struct {
+	KeyFile          string        `yaml:"key_file" env-default:""`
+	RotationInterval time.Duration `yaml:"rotation_interval" env-default:"1h"`
+}
+
+// TokenParams struct
+type TokenParams struct {
+	Name        string   `yaml:"name"`
+	Token       string   `yaml:"token"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// AuthParams struct
+type AuthParams struct {
+	AnonymousPermissions []string      `yaml:"anonymous_permissions"`
+	Tokens               []TokenParams `yaml:"tokens"`
+}
+
+// RedactionParams struct
+type RedactionParams struct {
+	Keys []string `yaml:"keys" env-default:"password,passwd,token,secret,*_password,*_token,*_secret,api_key"`
+}
+
+// LimitsParams struct
+type LimitsParams struct {
+	MaxBodySize       int64   `yaml:"max_body_size" env-default:"1048576"`
+	MaxImportSize     int64   `yaml:"max_import_size" env-default:"67108864"`
+	ClientRate        float64 `yaml:"client_rate" env-default:"10"`
+	ClientBurst       int     `yaml:"client_burst" env-default:"20"`
+	ServiceRate       float64 `yaml:"service_rate" env-default:"20"`
+	ServiceBurst      int     `yaml:"service_burst" env-default:"40"`
+	VersionsPerMinute int     `yaml:"versions_per_minute" env-default:"30"`
+}
+
+// MetricsParams struct
+type MetricsParams struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path" env-default:"/metrics"`
+	BindIp  string `yaml:"bind_ip" env-default:""`
+	Port    string `yaml:"port" env-default:""`
+}
+
+// GrpcParams struct
+type GrpcParams struct {
+	Enabled bool   `yaml:"enabled"`
+	BindIp  string `yaml:"bind_ip" env-default:""`
+	Port    string `yaml:"port" env-default:""`
+}
+
+// TracingParams struct
+type TracingParams struct {
+	Exporter    string  `yaml:"exporter" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+	ServiceName string  `yaml:"service_name" env-default:"config-server"`
+}
+
+// SyncParams struct
+type SyncParams struct {
+	Dir      string        `yaml:"dir" env-default:""`
+	Interval time.Duration `yaml:"interval" env-default:"1m"`
+	Prune    bool          `yaml:"prune" env-default:"false"`
+	DryRun   bool          `yaml:"dry_run" env-default:"false"`
+}
+
 // Config struct
 type Config struct {
-	Logging LoggingParams `yaml:"logging"`
-	Listen  ListenParams  `yaml:"listen"`
-	Storage StorageParams `yaml:"storage"`
+	Logging   LoggingParams   `yaml:"logging"`
+	Listen    ListenParams    `yaml:"listen"`
+	Storage   StorageParams   `yaml:"storage"`
+	Secrets   SecretsParams   `yaml:"secrets"`
+	Auth      AuthParams      `yaml:"auth"`
+	Redaction RedactionParams `yaml:"redaction"`
+	Limits    LimitsParams    `yaml:"limits"`
+	Metrics   MetricsParams   `yaml:"metrics"`
+	Tracing   TracingParams   `yaml:"tracing"`
+	Grpc      GrpcParams      `yaml:"grpc"`
+	Sync      SyncParams      `yaml:"sync"`
 }
 
 var instance *Config
@@ -51,7 +127,14 @@ var once sync.Once
 func GetConfig(args ...string) (*Config, error) {
 	var err error
 	once.Do(func() {
-		instance = &Config{}
+		// Значения по умолчанию для флагов, включенных по умолчанию.
+		// env-default для них не подходит: cleanenv подставляет его
+		// вместо любого нулевого значения, в том числе явного false
+		instance = &Config{
+			Metrics: MetricsParams{Enabled: true},
+			Tracing: TracingParams{Insecure: true},
+			Grpc:    GrpcParams{Enabled: true},
+		}
 		path := "config.yml"
 		if len(args) > 0 && args[0] != "" {
 			path = args[0]
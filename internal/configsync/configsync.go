@@ -0,0 +1,323 @@
+package configsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/jsondoc"
+	"go-cloud-camp/internal/secrets"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Действия плана синхронизации
+const (
+	ACTION_CREATE = "create"
+	ACTION_UPDATE = "update"
+	ACTION_DELETE = "delete"
+)
+
+// Target interface
+//
+// Хранилище конфигураций, которое приводится к содержимому каталога.
+// Функция Latest возвращает последнюю версию конфигурации с открытыми
+// секретными значениями в маркерах {"$secret": value} или nil, если
+// конфигурации сервиса нет.
+type Target interface {
+	Services(ctx context.Context) ([]string, error)
+	Latest(ctx context.Context, service string) (json.RawMessage, error)
+	Create(ctx context.Context, service string, data json.RawMessage) error
+	Update(ctx context.Context, service string, data json.RawMessage) error
+	Delete(ctx context.Context, service string) error
+}
+
+// Change struct
+//
+// Изменение конфигурации одного сервиса. Значения секретов
+// в изменениях по путям замаскированы.
+type Change struct {
+	Service string
+	Action  string
+	Data    json.RawMessage
+	Changes []jsondoc.Change
+
+	// Ошибка применения изменения
+	Err error
+}
+
+// Каталог без конфигураций при prune удалил бы все сервисы, например
+// если каталог указан неверно
+var ErrPruneAll = errors.New("directory has no configs, prune would delete all services")
+
+// Plan struct
+type Plan struct {
+	Changes   []*Change
+	Unchanged []string
+}
+
+// Count function
+//
+// Число изменений с действием action.
+func (p *Plan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Summary function
+func (p *Plan) Summary() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to delete, %d unchanged",
+		p.Count(ACTION_CREATE), p.Count(ACTION_UPDATE), p.Count(ACTION_DELETE), len(p.Unchanged))
+}
+
+// MakePlan function
+//
+// Сравнивает конфигурации каталога desired с последними версиями
+// конфигураций в хранилище. Конфигурации сервисов, которых нет
+// в каталоге, удаляются только при prune. Пустой каталог при prune
+// считается ошибкой, если в хранилище есть конфигурации.
+func MakePlan(ctx context.Context, target Target, desired map[string]json.RawMessage, prune bool) (*Plan, error) {
+	plan := &Plan{}
+
+	services := make([]string, 0, len(desired))
+	for service := range desired {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		change, err := compare(ctx, target, service, desired[service])
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", service, err)
+		}
+
+		if change == nil {
+			plan.Unchanged = append(plan.Unchanged, service)
+			continue
+		}
+
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if !prune {
+		return plan, nil
+	}
+
+	existing, err := target.Services(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(existing)
+
+	if len(desired) == 0 && len(existing) > 0 {
+		return nil, ErrPruneAll
+	}
+
+	for _, service := range existing {
+		if _, ok := desired[service]; !ok {
+			plan.Changes = append(plan.Changes, &Change{Service: service, Action: ACTION_DELETE})
+		}
+	}
+
+	return plan, nil
+}
+
+// compare function
+func compare(ctx context.Context, target Target, service string, data json.RawMessage) (*Change, error) {
+	doc, err := jsondoc.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	secretPaths := map[string]bool{}
+	plain := unwrapSecrets(doc, "", secretPaths)
+
+	current, err := target.Latest(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return &Change{
+			Service: service,
+			Action:  ACTION_CREATE,
+			Data:    data,
+			Changes: mask(added(plain), secretPaths),
+		}, nil
+	}
+
+	currentDoc, err := jsondoc.Decode(current)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сравниваются документы с маркерами, поэтому замена секретного
+	// значения обычным тоже считается изменением. Значения маскируются
+	// по путям секретов обоих документов
+	unwrapSecrets(currentDoc, "", secretPaths)
+
+	changes := jsondoc.Diff(currentDoc, doc)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	for i := range changes {
+		changes[i].Path = trimMarker(changes[i].Path)
+	}
+
+	return &Change{
+		Service: service,
+		Action:  ACTION_UPDATE,
+		Data:    data,
+		Changes: mask(changes, secretPaths),
+	}, nil
+}
+
+// Apply function
+//
+// Применяет изменения плана. Ошибка применения изменения одного
+// сервиса не прерывает применение остальных изменений, ошибки
+// сохраняются в изменениях плана.
+func Apply(ctx context.Context, target Target, plan *Plan) error {
+	failed := 0
+
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case ACTION_CREATE:
+			c.Err = target.Create(ctx, c.Service, c.Data)
+		case ACTION_UPDATE:
+			c.Err = target.Update(ctx, c.Service, c.Data)
+		case ACTION_DELETE:
+			c.Err = target.Delete(ctx, c.Service)
+		}
+
+		if c.Err != nil {
+			failed++
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d changes failed", failed, len(plan.Changes))
+	}
+
+	return nil
+}
+
+// unwrapSecrets function
+//
+// Заменяет объекты {"$secret": value} значениями value и запоминает
+// пути к ним.
+func unwrapSecrets(value interface{}, path string, paths map[string]bool) interface{} {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + jsondoc.PATH_SEPARATOR + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if inner, ok := v[secrets.SECRET_MARKER]; ok && len(v) == 1 {
+			paths[path] = true
+			return inner
+		}
+
+		result := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			result[key] = unwrapSecrets(inner, join(key), paths)
+		}
+		return result
+
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, inner := range v {
+			result[i] = unwrapSecrets(inner, join(strconv.Itoa(i)), paths)
+		}
+		return result
+	}
+
+	return value
+}
+
+// added function
+//
+// Все значения документа новой конфигурации.
+func added(doc interface{}) []jsondoc.Change {
+	values := jsondoc.Flatten(doc)
+
+	changes := make([]jsondoc.Change, 0, len(values))
+	for path, value := range values {
+		changes = append(changes, jsondoc.Change{Path: path, New: value})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// mask function
+//
+// Маскирует значения изменений по путям секретов и вложенным в них путям.
+func mask(changes []jsondoc.Change, secretPaths map[string]bool) []jsondoc.Change {
+	if len(secretPaths) == 0 {
+		return changes
+	}
+
+	redacted, _ := json.Marshal(secrets.REDACTED_VALUE)
+
+	for i, c := range changes {
+		if !underSecret(c.Path, secretPaths) {
+			continue
+		}
+
+		if c.Old != nil {
+			changes[i].Old = redacted
+		}
+		if c.New != nil {
+			changes[i].New = redacted
+		}
+	}
+
+	return changes
+}
+
+// trimMarker function
+//
+// Путь значения внутри маркера {"$secret": value} совпадает с путем
+// самого секрета.
+func trimMarker(path string) string {
+	parts := strings.Split(path, jsondoc.PATH_SEPARATOR)
+
+	result := parts[:0]
+	for _, part := range parts {
+		if part != secrets.SECRET_MARKER {
+			result = append(result, part)
+		}
+	}
+
+	return strings.Join(result, jsondoc.PATH_SEPARATOR)
+}
+
+// underSecret function
+func underSecret(path string, secretPaths map[string]bool) bool {
+	for p := range secretPaths {
+		if path == p || p == "" || strings.HasPrefix(path, p+jsondoc.PATH_SEPARATOR) {
+			return true
+		}
+	}
+
+	return false
+}
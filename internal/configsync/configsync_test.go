@@ -0,0 +1,204 @@
+package configsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-cloud-camp/internal/config"
+	"go-cloud-camp/internal/secrets"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memTarget struct
+//
+// Хранилище последних версий конфигураций для тестов плана. Latest
+// возвращает секретные значения в маркерах, как storageTarget.
+type memTarget struct {
+	configs map[string]json.RawMessage
+	failed  map[string]error
+}
+
+// Services function
+func (t *memTarget) Services(ctx context.Context) ([]string, error) {
+	services := make([]string, 0, len(t.configs))
+	for service := range t.configs {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	return services, nil
+}
+
+// Latest function
+func (t *memTarget) Latest(ctx context.Context, service string) (json.RawMessage, error) {
+	return t.configs[service], nil
+}
+
+// Create function
+func (t *memTarget) Create(ctx context.Context, service string, data json.RawMessage) error {
+	return t.set(service, data)
+}
+
+// Update function
+func (t *memTarget) Update(ctx context.Context, service string, data json.RawMessage) error {
+	return t.set(service, data)
+}
+
+// Delete function
+func (t *memTarget) Delete(ctx context.Context, service string) error {
+	delete(t.configs, service)
+	return nil
+}
+
+// set function
+func (t *memTarget) set(service string, data json.RawMessage) error {
+	if err := t.failed[service]; err != nil {
+		return err
+	}
+
+	t.configs[service] = data
+	return nil
+}
+
+// configs function
+func configs(kv ...string) map[string]json.RawMessage {
+	result := map[string]json.RawMessage{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		result[kv[i]] = json.RawMessage(kv[i+1])
+	}
+
+	return result
+}
+
+func TestMakePlan(t *testing.T) {
+	target := &memTarget{configs: configs(
+		"same", `{"a":1}`,
+		"changed", `{"a":1,"b":2}`,
+		"legacy", `{"a":1}`,
+	)}
+	desired := configs(
+		"same", `{"a":1}`,
+		"changed", `{"a":1,"b":3}`,
+		"new", `{"c":{"d":true}}`,
+	)
+	ctx := context.Background()
+
+	plan, err := MakePlan(ctx, target, desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := plan.Summary(); got != "1 to create, 1 to update, 0 to delete, 1 unchanged" {
+		t.Fatalf("Summary() = %q", got)
+	}
+
+	for _, c := range plan.Changes {
+		switch c.Service {
+		case "changed":
+			if c.Action != ACTION_UPDATE || len(c.Changes) != 1 || c.Changes[0].Path != "b" {
+				t.Errorf("change of %q = %s %+v", c.Service, c.Action, c.Changes)
+			}
+		case "new":
+			if c.Action != ACTION_CREATE || len(c.Changes) != 1 || c.Changes[0].Path != "c.d" {
+				t.Errorf("change of %q = %s %+v", c.Service, c.Action, c.Changes)
+			}
+		default:
+			t.Errorf("unexpected change of %q", c.Service)
+		}
+	}
+
+	plan, err = MakePlan(ctx, target, desired, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Count(ACTION_DELETE) != 1 || plan.Changes[len(plan.Changes)-1].Service != "legacy" {
+		t.Fatalf("prune plan = %s", plan.Summary())
+	}
+
+	if err := Apply(ctx, target, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	// После применения плана конфигурации совпадают с каталогом
+	plan, err = MakePlan(ctx, target, desired, true)
+	if err != nil || len(plan.Changes) != 0 {
+		t.Fatalf("plan after apply = %v, %v", plan, err)
+	}
+}
+
+func TestMakePlanRefusesPruneAll(t *testing.T) {
+	target := &memTarget{configs: configs("svc", `{"a":1}`)}
+	ctx := context.Background()
+
+	if _, err := MakePlan(ctx, target, configs(), true); !errors.Is(err, ErrPruneAll) {
+		t.Fatalf("MakePlan() error = %v, want %v", err, ErrPruneAll)
+	}
+
+	plan, err := MakePlan(ctx, target, configs(), false)
+	if err != nil || len(plan.Changes) != 0 {
+		t.Fatalf("MakePlan() = %v, %v", plan, err)
+	}
+
+	// Пустой каталог и пустое хранилище уже синхронизированы
+	plan, err = MakePlan(ctx, &memTarget{configs: configs()}, configs(), true)
+	if err != nil || len(plan.Changes) != 0 {
+		t.Fatalf("MakePlan() = %v, %v", plan, err)
+	}
+}
+
+func TestMakePlanSecrets(t *testing.T) {
+	target := &memTarget{configs: configs(
+		"same", `{"user":"u","password":{"$secret":"p"}}`,
+		"rotated", `{"password":{"$secret":"old-secret"}}`,
+		"unmarked", `{"password":{"$secret":"p"}}`,
+	)}
+	desired := configs(
+		"same", `{"user":"u","password":{"$secret":"p"}}`,
+		"rotated", `{"password":{"$secret":"new-secret"}}`,
+		"unmarked", `{"password":"p"}`,
+		"created", `{"token":{"$secret":"t"}}`,
+	)
+
+	plan, err := MakePlan(context.Background(), target, desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Unchanged) != 1 || plan.Unchanged[0] != "same" {
+		t.Fatalf("unchanged = %v", plan.Unchanged)
+	}
+
+	redacted := `"` + secrets.REDACTED_VALUE + `"`
+
+	for _, c := range plan.Changes {
+		if len(c.Changes) == 0 {
+			t.Errorf("change of %q has no values", c.Service)
+		}
+
+		for _, change := range c.Changes {
+			if strings.Contains(change.Path, secrets.SECRET_MARKER) {
+				t.Errorf("change of %q has marker in path %q", c.Service, change.Path)
+			}
+
+			for _, value := range []json.RawMessage{change.Old, change.New} {
+				if value != nil && string(value) != redacted {
+					t.Errorf("change of %q reveals secret value %s at %q", c.Service, value, change.Path)
+				}
+			}
+		}
+	}
+}
+
+func TestCreateRejectsInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		cfg := &config.SyncParams{Dir: t.TempDir(), Interval: interval}
+
+		if _, err := Create(cfg, nil, nil); err == nil {
+			t.Errorf("Create() with interval %s succeeded", interval)
+		}
+	}
+}
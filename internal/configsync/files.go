@@ -0,0 +1,85 @@
+package configsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/jsondoc"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Расширения файлов конфигураций в каталоге
+var extensions = []string{".json", ".yaml", ".yml"}
+
+// LoadDir function
+//
+// Читает конфигурации сервисов из файлов <service>.json, <service>.yaml
+// и <service>.yml каталога dir. Файлы YAML преобразуются в JSON, скрытые
+// файлы, подкаталоги и файлы с другими расширениями пропускаются.
+func LoadDir(dir string) (map[string]json.RawMessage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := map[string]json.RawMessage{}
+	files := map[string]string{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		service, ok := serviceName(name)
+		if !ok {
+			continue
+		}
+
+		if other, ok := files[service]; ok {
+			return nil, fmt.Errorf("config of service %q is defined in both %s and %s", service, other, name)
+		}
+		files[service] = name
+
+		data, err := loadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		configs[service] = data
+	}
+
+	return configs, nil
+}
+
+// serviceName function
+func serviceName(file string) (string, bool) {
+	ext := filepath.Ext(file)
+
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			service := strings.TrimSuffix(file, ext)
+			return service, service != ""
+		}
+	}
+
+	return "", false
+}
+
+// loadFile function
+func loadFile(path string) (json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty config")
+	}
+
+	return jsondoc.FromYAML(data)
+}
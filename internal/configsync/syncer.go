@@ -0,0 +1,179 @@
+package configsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/config"
+	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/storage"
+	"time"
+)
+
+// Syncer struct
+//
+// Периодически приводит конфигурации в хранилище к содержимому
+// каталога с файлами конфигураций.
+type Syncer struct {
+	cfg    config.SyncParams
+	log    *logging.Logger
+	target Target
+
+	// Остановка синхронизации и ожидание ее завершения
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// Create function
+func Create(cfg *config.SyncParams, s *storage.AppStorage, log *logging.Logger) (*Syncer, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("sync interval must be positive, got %s", cfg.Interval)
+	}
+
+	return &Syncer{
+		cfg:     *cfg,
+		log:     log,
+		target:  &storageTarget{storage: s},
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// Start function
+//
+// В отдельной горутине синхронизирует конфигурации сразу после
+// запуска и затем с периодом interval.
+func (s *Syncer) Start() {
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			s.Sync(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop function
+//
+// Прерывает текущую синхронизацию и ожидает ее завершения.
+func (s *Syncer) Stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	<-s.stopped
+}
+
+// Sync function
+//
+// Выполняет одну синхронизацию. В режиме dry_run план изменений
+// только записывается в журнал.
+func (s *Syncer) Sync(ctx context.Context) {
+	desired, err := LoadDir(s.cfg.Dir)
+	if err != nil {
+		s.log.Errorw("config sync aborted with error", "dir", s.cfg.Dir, "error", err)
+		return
+	}
+
+	plan, err := MakePlan(ctx, s.target, desired, s.cfg.Prune)
+	if err != nil {
+		s.log.Errorw("config sync aborted with error", "dir", s.cfg.Dir, "error", err)
+		return
+	}
+
+	if len(plan.Changes) == 0 {
+		s.log.Debugw("configs are in sync", "dir", s.cfg.Dir, "services", len(plan.Unchanged))
+		return
+	}
+
+	if s.cfg.DryRun {
+		for _, c := range plan.Changes {
+			s.log.Infow("config sync planned", "service", c.Service, "action", c.Action, "paths", paths(c))
+		}
+		s.log.Infow("config sync dry run completed", "dir", s.cfg.Dir, "plan", plan.Summary())
+		return
+	}
+
+	err = Apply(ctx, s.target, plan)
+
+	for _, c := range plan.Changes {
+		if c.Err != nil {
+			s.log.Errorw("config sync failed", "service", c.Service, "action", c.Action, "error", c.Err)
+			continue
+		}
+		s.log.Infow("config synced", "service", c.Service, "action", c.Action, "paths", paths(c))
+	}
+
+	if err != nil {
+		s.log.Errorw("config sync completed with errors", "dir", s.cfg.Dir, "error", err)
+		return
+	}
+
+	s.log.Infow("config sync completed", "dir", s.cfg.Dir, "plan", plan.Summary())
+}
+
+// paths function
+//
+// Пути измененных значений, сами значения в журнал не записываются.
+func paths(c *Change) []string {
+	result := make([]string, 0, len(c.Changes))
+	for _, change := range c.Changes {
+		result = append(result, change.Path)
+	}
+
+	return result
+}
+
+// storageTarget struct
+type storageTarget struct {
+	storage *storage.AppStorage
+}
+
+// Services function
+func (t *storageTarget) Services(ctx context.Context) ([]string, error) {
+	return t.storage.List(ctx)
+}
+
+// Latest function
+func (t *storageTarget) Latest(ctx context.Context, service string) (json.RawMessage, error) {
+	record, err := t.storage.PeekMarked(ctx, service, 0)
+	if errors.Is(err, common.ErrNotFound) || errors.Is(err, common.ErrServiceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return record.Data, nil
+}
+
+// Create function
+func (t *storageTarget) Create(ctx context.Context, service string, data json.RawMessage) error {
+	_, err := t.storage.Create(ctx, &common.RequestData{Service: service, Data: data})
+	return err
+}
+
+// Update function
+func (t *storageTarget) Update(ctx context.Context, service string, data json.RawMessage) error {
+	_, err := t.storage.Update(ctx, &common.RequestData{Service: service, Data: data})
+	return err
+}
+
+// Delete function
+func (t *storageTarget) Delete(ctx context.Context, service string) error {
+	return t.storage.Delete(ctx, service, 0)
+}
@@ -0,0 +1,11 @@
+package grpcapi
+
+import (
+	"go-cloud-camp/internal/grpcjson"
+
+	"google.golang.org/grpc/encoding"
+)
+
+func init() {
+	encoding.RegisterCodec(grpcjson.Codec{})
+}
@@ -0,0 +1,72 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/common"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCodes variable
+var errorCodes = map[string]codes.Code{
+	common.CODE_NOT_FOUND:          codes.NotFound,
+	common.CODE_SERVICE_NOT_FOUND:  codes.NotFound,
+	common.CODE_ALREADY_EXISTS:     codes.AlreadyExists,
+	common.CODE_CONFIG_IN_USE:      codes.FailedPrecondition,
+	common.CODE_EMPTY_SERVICE_NAME: codes.InvalidArgument,
+	common.CODE_INVALID_JSON:       codes.InvalidArgument,
+	common.CODE_INVALID_REQUEST:    codes.InvalidArgument,
+	common.CODE_INVALID_VERSION:    codes.InvalidArgument,
+	common.CODE_SECRETS_DISABLED:   codes.InvalidArgument,
+	common.CODE_INVALID_ARCHIVE:    codes.InvalidArgument,
+	common.CODE_FORBIDDEN:          codes.PermissionDenied,
+	common.CODE_BODY_TOO_LARGE:     codes.ResourceExhausted,
+	common.CODE_UNAUTHORIZED:       codes.Unauthenticated,
+	common.CODE_RATE_LIMITED:       codes.ResourceExhausted,
+	common.CODE_PRECONDITION:       codes.Aborted,
+	common.CODE_INTERNAL:           codes.Internal,
+}
+
+// toStatus function
+//
+// Преобразует ошибку в статус gRPC. Машиночитаемый код ошибки
+// возвращается отдельно и передается клиенту в метаданных ответа.
+func toStatus(err error) (string, error) {
+	if err == nil {
+		return common.EMPTY_STRING, nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return common.EMPTY_STRING, err
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return common.EMPTY_STRING, status.FromContextError(err).Err()
+	}
+
+	code := common.ErrorCode(err)
+
+	grpcCode, ok := errorCodes[code]
+	if !ok {
+		grpcCode = codes.Internal
+	}
+
+	msg := err.Error()
+
+	var validationErr *common.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, p := range validationErr.Params {
+			msg = fmt.Sprintf("%s; %s %s", msg, p.Name, p.Reason)
+		}
+	}
+
+	// Подробности внутренних ошибок клиенту не передаются
+	if code == common.CODE_INTERNAL {
+		msg = "internal error"
+	}
+
+	return code, status.Error(grpcCode, msg)
+}
@@ -0,0 +1,76 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"go-cloud-camp/internal/common"
+	"time"
+)
+
+// GetRequest struct
+//
+// Secrets со значением common.SECRETS_MARKED запрашивает открытые
+// секретные значения в маркерах {"$secret": value}.
+type GetRequest struct {
+	Service string `json:"service"`
+	Version int    `json:"version"`
+	Secrets string `json:"secrets,omitempty"`
+}
+
+// Config struct
+type Config struct {
+	Service   string          `json:"service"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+	ETag      string          `json:"etag"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ListRequest struct
+type ListRequest struct{}
+
+// ListReply struct
+type ListReply struct {
+	Services []string `json:"services"`
+}
+
+// VersionsRequest struct
+type VersionsRequest struct {
+	Service string `json:"service"`
+}
+
+// VersionsReply struct
+type VersionsReply struct {
+	Service  string                `json:"service"`
+	Versions []*common.VersionInfo `json:"versions"`
+}
+
+// WriteRequest type
+type WriteRequest = common.RequestData
+
+// WriteReply type
+type WriteReply = common.VersionInfo
+
+// DeleteRequest struct
+type DeleteRequest struct {
+	Service string `json:"service"`
+	Version int    `json:"version"`
+}
+
+// DeleteReply struct
+type DeleteReply struct{}
+
+// WatchRequest struct
+type WatchRequest struct {
+	Service string `json:"service"`
+}
+
+// newConfig function
+func newConfig(record *common.ConfigRecord) *Config {
+	return &Config{
+		Service:   record.Service,
+		Version:   record.Version,
+		CreatedAt: record.CreatedAt,
+		ETag:      record.ETag,
+		Data:      record.Data,
+	}
+}
@@ -0,0 +1,175 @@
+package grpcapi
+
+import (
+	"context"
+	"go-cloud-camp/internal/auth"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/logging"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Ключи метаданных запросов и ответов
+const (
+	AUTHORIZATION_KEY = "authorization"
+	REQUEST_ID_KEY    = "x-request-id"
+	ERROR_CODE_KEY    = "x-error-code"
+	IF_MATCH_KEY      = "if-match"
+)
+
+// call struct
+type call struct {
+	start     time.Time
+	requestID string
+	logger    *logging.Logger
+	actor     string
+	code      string
+}
+
+// serverStream struct
+//
+// Поток с контекстом, дополненным обработчиками вызова.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context function
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// unaryInterceptor function
+func (s *configService) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, c := s.begin(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(REQUEST_ID_KEY, c.requestID))
+
+	var resp interface{}
+
+	ctx, err := s.authorize(ctx, c)
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+
+	err = s.finish(ctx, c, info.FullMethod, err)
+	if c.code != common.EMPTY_STRING {
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(ERROR_CODE_KEY, c.code))
+	}
+
+	return resp, err
+}
+
+// streamInterceptor function
+func (s *configService) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, c := s.begin(ss.Context())
+	_ = ss.SetHeader(metadata.Pairs(REQUEST_ID_KEY, c.requestID))
+
+	ctx, err := s.authorize(ctx, c)
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+
+	err = s.finish(ctx, c, info.FullMethod, err)
+	if c.code != common.EMPTY_STRING {
+		ss.SetTrailer(metadata.Pairs(ERROR_CODE_KEY, c.code))
+	}
+
+	return err
+}
+
+// begin function
+//
+// Назначает вызову идентификатор (или использует переданный клиентом
+// в метаданных x-request-id) и добавляет в контекст логгер с этим
+// идентификатором.
+func (s *configService) begin(ctx context.Context) (context.Context, *call) {
+	c := &call{
+		start:     time.Now(),
+		requestID: common.RequestID(metadataValue(ctx, REQUEST_ID_KEY)),
+		actor:     auth.ANONYMOUS,
+	}
+	c.logger = &logging.Logger{SugaredLogger: s.log.With("request_id", c.requestID)}
+
+	return logging.WithContext(ctx, c.logger), c
+}
+
+// authorize function
+//
+// Определяет клиента по метаданным authorization и проверяет
+// ограничение частоты запросов клиента.
+func (s *configService) authorize(ctx context.Context, c *call) (context.Context, error) {
+	identity, err := s.auth.IdentifyHeader(metadataValue(ctx, AUTHORIZATION_KEY))
+	if err != nil {
+		return ctx, err
+	}
+
+	c.actor = identity.Name
+	ctx = auth.WithIdentity(ctx, identity)
+
+	if _, ok := s.limits.AllowClient(clientKey(ctx)); !ok {
+		return ctx, common.ErrRateLimited
+	}
+
+	return ctx, nil
+}
+
+// finish function
+//
+// Преобразует ошибку вызова в статус gRPC и записывает
+// в журнал одну строку по завершении вызова.
+func (s *configService) finish(ctx context.Context, c *call, method string, err error) error {
+	c.code, err = toStatus(err)
+
+	remoteAddr := common.EMPTY_STRING
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	c.logger.Infow("rpc completed",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration", time.Since(c.start),
+		"remote_addr", remoteAddr,
+		"actor", c.actor,
+	)
+
+	return err
+}
+
+// clientKey function
+func clientKey(ctx context.Context) string {
+	if identity := auth.FromContext(ctx); identity != nil && identity.Name != auth.ANONYMOUS {
+		return identity.Name
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return common.EMPTY_STRING
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// metadataValue function
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return common.EMPTY_STRING
+	}
+
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return common.EMPTY_STRING
+}
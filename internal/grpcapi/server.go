@@ -0,0 +1,298 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"go-cloud-camp/internal/auth"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/metrics"
+	"go-cloud-camp/internal/ratelimit"
+	"go-cloud-camp/internal/storage"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// configService struct
+type configService struct {
+	log     *logging.Logger
+	storage *storage.AppStorage
+	auth    *auth.Authenticator
+	limits  *ratelimit.Limiter
+	metrics *metrics.Metrics
+}
+
+// Create function
+func Create(l *logging.Logger, s *storage.AppStorage, a *auth.Authenticator, lim *ratelimit.Limiter, m *metrics.Metrics) *grpc.Server {
+	svc := &configService{
+		log:     l,
+		storage: s,
+		auth:    a,
+		limits:  lim,
+		metrics: m,
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(svc.unaryInterceptor),
+		grpc.ChainStreamInterceptor(svc.streamInterceptor),
+	}
+
+	if maxSize := lim.MaxBodySize(); maxSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(maxSize)))
+	}
+
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&ServiceDesc, svc)
+
+	return server
+}
+
+// Get function
+func (s *configService) Get(ctx context.Context, in *GetRequest) (*Config, error) {
+	if err := validateService(in.Service, in.Version); err != nil {
+		return nil, err
+	}
+
+	if err := s.allowService(in.Service); err != nil {
+		return nil, err
+	}
+
+	var record *common.ConfigRecord
+	var err error
+
+	switch in.Secrets {
+	case common.EMPTY_STRING:
+		record, err = s.storage.Read(ctx, in.Service, in.Version, revealSecrets(ctx))
+	case common.SECRETS_MARKED:
+		if !revealSecrets(ctx) {
+			return nil, common.ErrForbidden
+		}
+		record, err = s.storage.ReadMarked(ctx, in.Service, in.Version)
+	default:
+		return nil, common.NewValidationError(common.ErrInvalidRequest, common.SECRETS_PARAM, "must be "+common.SECRETS_MARKED)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newConfig(record), nil
+}
+
+// List function
+func (s *configService) List(ctx context.Context, in *ListRequest) (*ListReply, error) {
+	services, err := s.storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(services)
+
+	return &ListReply{Services: services}, nil
+}
+
+// Versions function
+func (s *configService) Versions(ctx context.Context, in *VersionsRequest) (*VersionsReply, error) {
+	if err := validateService(in.Service, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.allowService(in.Service); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.storage.Versions(ctx, in.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionsReply{Service: in.Service, Versions: versions}, nil
+}
+
+// Create function
+func (s *configService) Create(ctx context.Context, in *WriteRequest) (*WriteReply, error) {
+	if err := validateWrite(in); err != nil {
+		return nil, err
+	}
+
+	if err := s.allowService(in.Service); err != nil {
+		return nil, err
+	}
+
+	refund, err := s.reserveVersion(in.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.storage.Create(ctx, in)
+	if err != nil {
+		refund()
+	}
+
+	return reply, err
+}
+
+// Update function
+func (s *configService) Update(ctx context.Context, in *WriteRequest) (*WriteReply, error) {
+	if err := validateWrite(in); err != nil {
+		return nil, err
+	}
+
+	if err := s.allowService(in.Service); err != nil {
+		return nil, err
+	}
+
+	refund, err := s.reserveVersion(in.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	// ETag последней версии передается в метаданных, как заголовок If-Match
+	in.IfMatch = metadataValue(ctx, IF_MATCH_KEY)
+
+	reply, err := s.storage.Update(ctx, in)
+	if err != nil {
+		refund()
+	}
+
+	return reply, err
+}
+
+// Delete function
+func (s *configService) Delete(ctx context.Context, in *DeleteRequest) (*DeleteReply, error) {
+	if err := validateService(in.Service, in.Version); err != nil {
+		return nil, err
+	}
+
+	if err := s.allowService(in.Service); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Delete(ctx, in.Service, in.Version); err != nil {
+		return nil, err
+	}
+
+	return &DeleteReply{}, nil
+}
+
+// Watch function
+//
+// Отправляет клиенту последнюю версию конфига (если она есть),
+// а затем каждую новую версию по мере их сохранения.
+func (s *configService) Watch(in *WatchRequest, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+
+	if err := validateService(in.Service, 0); err != nil {
+		return err
+	}
+
+	if err := s.allowService(in.Service); err != nil {
+		return err
+	}
+
+	s.metrics.WatchSubscribers(1)
+	defer s.metrics.WatchSubscribers(-1)
+
+	// Подписываемся до чтения текущей версии, чтобы не пропустить
+	// версии, сохраненные между чтением и подпиской
+	updates, cancel := s.storage.Watch(in.Service)
+	defer cancel()
+
+	reveal := revealSecrets(ctx)
+	last := 0
+
+	record, err := s.storage.Read(ctx, in.Service, 0, reveal)
+	switch {
+	case err == nil:
+		if err := stream.SendMsg(newConfig(record)); err != nil {
+			return err
+		}
+		last = record.Version
+	case !errors.Is(err, common.ErrNotFound):
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case info, ok := <-updates:
+			if !ok {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+
+			if info.Version <= last {
+				continue
+			}
+
+			record, err := s.storage.Read(ctx, in.Service, info.Version, reveal)
+			if errors.Is(err, common.ErrNotFound) {
+				// Версия удалена до отправки подписчику
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := stream.SendMsg(newConfig(record)); err != nil {
+				return err
+			}
+			last = record.Version
+		}
+	}
+}
+
+// allowService function
+func (s *configService) allowService(service string) error {
+	if _, ok := s.limits.AllowService(service); !ok {
+		return common.ErrRateLimited
+	}
+
+	return nil
+}
+
+// reserveVersion function
+//
+// Резерв нужно вернуть функцией refund, если версия не была создана.
+func (s *configService) reserveVersion(service string) (func(), error) {
+	_, refund, ok := s.limits.ReserveVersion(service)
+	if !ok {
+		return nil, common.ErrRateLimited
+	}
+
+	return refund, nil
+}
+
+// revealSecrets function
+func revealSecrets(ctx context.Context) bool {
+	return auth.FromContext(ctx).Has(auth.PERMISSION_READ_SECRETS)
+}
+
+// validateService function
+func validateService(service string, version int) error {
+	if service == common.EMPTY_STRING {
+		return common.NewValidationError(common.ErrEmptyServiceName, "service", "must not be empty")
+	}
+
+	// Версия 0 означает последнюю версию конфига
+	if version < 0 {
+		return common.NewValidationError(common.ErrInvalidVersion, "version", "must not be negative")
+	}
+
+	return nil
+}
+
+// validateWrite function
+func validateWrite(in *WriteRequest) error {
+	if err := validateService(in.Service, 0); err != nil {
+		return err
+	}
+
+	if len(in.Data) == 0 || string(in.Data) == "null" {
+		return common.NewValidationError(common.ErrNotValidJsonData, "data", "must be a JSON value")
+	}
+
+	return nil
+}
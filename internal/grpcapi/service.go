@@ -0,0 +1,90 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const SERVICE_NAME = "configserver.v1.ConfigService"
+
+// Полные имена методов сервиса
+const (
+	METHOD_GET    = "/" + SERVICE_NAME + "/Get"
+	METHOD_LIST   = "/" + SERVICE_NAME + "/List"
+	METHOD_CREATE = "/" + SERVICE_NAME + "/Create"
+	METHOD_UPDATE = "/" + SERVICE_NAME + "/Update"
+	METHOD_DELETE = "/" + SERVICE_NAME + "/Delete"
+	METHOD_WATCH  = "/" + SERVICE_NAME + "/Watch"
+
+	METHOD_VERSIONS = "/" + SERVICE_NAME + "/Versions"
+)
+
+// ConfigServiceServer interface
+type ConfigServiceServer interface {
+	Get(context.Context, *GetRequest) (*Config, error)
+	List(context.Context, *ListRequest) (*ListReply, error)
+	Versions(context.Context, *VersionsRequest) (*VersionsReply, error)
+	Create(context.Context, *WriteRequest) (*WriteReply, error)
+	Update(context.Context, *WriteRequest) (*WriteReply, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteReply, error)
+	Watch(*WatchRequest, grpc.ServerStream) error
+}
+
+// ServiceDesc variable
+//
+// Описание сервиса для grpc.Server, аналог кода, который
+// генерирует protoc-gen-go-grpc.
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: SERVICE_NAME,
+	HandlerType: (*ConfigServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("Get", METHOD_GET, ConfigServiceServer.Get),
+		unaryMethod("List", METHOD_LIST, ConfigServiceServer.List),
+		unaryMethod("Versions", METHOD_VERSIONS, ConfigServiceServer.Versions),
+		unaryMethod("Create", METHOD_CREATE, ConfigServiceServer.Create),
+		unaryMethod("Update", METHOD_UPDATE, ConfigServiceServer.Update),
+		unaryMethod("Delete", METHOD_DELETE, ConfigServiceServer.Delete),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       watchHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// unaryMethod function
+func unaryMethod[Req any, Reply any](name string, fullMethod string, call func(ConfigServiceServer, context.Context, *Req) (*Reply, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(Req)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+
+			if interceptor == nil {
+				return call(srv.(ConfigServiceServer), ctx, in)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(ConfigServiceServer), ctx, req.(*Req))
+			}
+
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+// watchHandler function
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(WatchRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+
+	return srv.(ConfigServiceServer).Watch(in, stream)
+}
@@ -0,0 +1,26 @@
+package grpcjson
+
+import "encoding/json"
+
+// Сообщения сервиса gRPC кодируются в JSON (content-type application/grpc+json)
+const CODEC_NAME = "json"
+
+// Codec struct
+//
+// Кодек сообщений сервиса gRPC, общий для сервера и клиента.
+type Codec struct{}
+
+// Marshal function
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal function
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Name function
+func (Codec) Name() string {
+	return CODEC_NAME
+}
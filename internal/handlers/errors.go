@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-cloud-camp/internal/common"
+	"net/http"
+)
+
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+// Problem struct
+//
+// Описание ошибки в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+
+	InvalidParams []common.InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// errorStatus variable
+var errorStatus = map[string]int{
+	common.CODE_NOT_FOUND:          http.StatusNotFound,
+	common.CODE_SERVICE_NOT_FOUND:  http.StatusNotFound,
+	common.CODE_ALREADY_EXISTS:     http.StatusConflict,
+	common.CODE_CONFIG_IN_USE:      http.StatusConflict,
+	common.CODE_EMPTY_SERVICE_NAME: http.StatusBadRequest,
+	common.CODE_INVALID_JSON:       http.StatusBadRequest,
+	common.CODE_INVALID_REQUEST:    http.StatusBadRequest,
+	common.CODE_INVALID_VERSION:    http.StatusBadRequest,
+	common.CODE_SECRETS_DISABLED:   http.StatusBadRequest,
+	common.CODE_INVALID_ARCHIVE:    http.StatusBadRequest,
+	common.CODE_FORBIDDEN:          http.StatusForbidden,
+	common.CODE_BODY_TOO_LARGE:     http.StatusRequestEntityTooLarge,
+	common.CODE_UNAUTHORIZED:       http.StatusUnauthorized,
+	common.CODE_RATE_LIMITED:       http.StatusTooManyRequests,
+	common.CODE_PRECONDITION:       http.StatusPreconditionFailed,
+	common.CODE_INTERNAL:           http.StatusInternalServerError,
+}
+
+// writeError function
+func (h *AppHandlers) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	code := common.ErrorCode(err)
+
+	status, ok := errorStatus[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	problem := &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.Error(),
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: w.Header().Get(REQUEST_ID_HEADER),
+	}
+
+	var validationErr *common.ValidationError
+	if errors.As(err, &validationErr) {
+		problem.InvalidParams = validationErr.Params
+	}
+
+	// Подробности внутренних ошибок клиенту не передаются
+	if code == common.CODE_INTERNAL {
+		problem.Detail = http.StatusText(status)
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", PROBLEM_CONTENT_TYPE)
+	w.WriteHeader(status)
+	if _, err = w.Write(body); err != nil {
+		h.LogDebugRequestDetails("http.ResponseWriter was called with an error", err, r)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/common"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteErrorProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"not found", common.ErrServiceNotFound, http.StatusNotFound, common.CODE_SERVICE_NOT_FOUND, "service not found"},
+		{"conflict", common.ErrAlreadyCreated, http.StatusConflict, common.CODE_ALREADY_EXISTS, "config already created"},
+		{"wrapped", fmt.Errorf("delete: %w", common.ErrConfigIsUsed), http.StatusConflict, common.CODE_CONFIG_IN_USE, "delete: config is used"},
+		{"internal", errors.New("connection refused"), http.StatusInternalServerError, common.CODE_INTERNAL, "Internal Server Error"},
+	}
+
+	h := &AppHandlers{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/config?service=svc", nil)
+			rec := httptest.NewRecorder()
+			rec.Header().Set(REQUEST_ID_HEADER, "req-1")
+
+			h.writeError(rec, r, tt.err)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != PROBLEM_CONTENT_TYPE {
+				t.Fatalf("Content-Type = %q", ct)
+			}
+
+			problem := &Problem{}
+			if err := json.Unmarshal(rec.Body.Bytes(), problem); err != nil {
+				t.Fatal(err)
+			}
+
+			// Подробности внутренней ошибки не раскрываются
+			if problem.Status != tt.status || problem.Code != tt.code || problem.Detail != tt.detail ||
+				problem.Instance != "/config" || problem.RequestID != "req-1" {
+				t.Fatalf("problem = %+v", problem)
+			}
+		})
+	}
+}
@@ -1,193 +1,547 @@
 package handlers
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"fmt"
+	"go-cloud-camp/internal/archive"
+	"go-cloud-camp/internal/auth"
 	"go-cloud-camp/internal/common"
 	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/metrics"
+	"go-cloud-camp/internal/openapi"
+	"go-cloud-camp/internal/ratelimit"
+	"go-cloud-camp/internal/redact"
 	"go-cloud-camp/internal/storage"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 const (
-	configURL = "/config"
+	configURL   = "/config"
+	livenessURL = "/healthz"
+	readyURL    = "/readyz"
+	openapiURL  = "/openapi.json"
+
+	// Маршруты ресурсов API v1, имя сервиса и номер версии передаются в пути
+	servicesURL        = "/v1/services"
+	serviceConfigURL   = "/v1/services/:service/config"
+	serviceVersionsURL = "/v1/services/:service/versions"
+	serviceVersionURL  = "/v1/services/:service/versions/:version"
+
+	// Маршруты выгрузки и загрузки всех конфигов, нужно разрешение admin
+	exportURL = "/admin/export"
+	importURL = "/admin/import"
+)
+
+// Режимы загрузки архива конфигов
+const (
+	IMPORT_MERGE   = "merge"
+	IMPORT_REPLACE = "replace"
 )
 
+// Таймаут проверки доступности хранилища
+const READINESS_TIMEOUT = time.Second
+
 // AppHandlers struct
 type AppHandlers struct {
-	Log     *logging.Logger
-	Storage *storage.AppStorage
+	Log      *logging.Logger
+	Storage  *storage.AppStorage
+	Auth     *auth.Authenticator
+	Redactor *redact.Policy
+	Limits   *ratelimit.Limiter
+	Metrics  *metrics.Metrics
+
+	ready atomic.Bool
 }
 
 // Create function
-func Create(l *logging.Logger, s *storage.AppStorage) *AppHandlers {
-	return &AppHandlers{
-		Log:     l,
-		Storage: s,
+func Create(l *logging.Logger, s *storage.AppStorage, a *auth.Authenticator, rd *redact.Policy, lim *ratelimit.Limiter, m *metrics.Metrics) *AppHandlers {
+	h := &AppHandlers{
+		Log:      l,
+		Storage:  s,
+		Auth:     a,
+		Redactor: rd,
+		Limits:   lim,
+		Metrics:  m,
+	}
+	h.ready.Store(true)
+
+	return h
+}
+
+// route struct
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+
+	// Маршрут без аутентификации и ограничений частоты запросов
+	public bool
+
+	operation *openapi.Operation
+}
+
+// servicesList struct
+type servicesList struct {
+	Services []string `json:"services"`
+}
+
+// versionsList struct
+type versionsList struct {
+	Service  string                `json:"service"`
+	Versions []*common.VersionInfo `json:"versions"`
+}
+
+// routes function
+//
+// Таблица маршрутов сервера. По ней регистрируются обработчики
+// и строится спецификация OpenAPI.
+func (h *AppHandlers) routes() []route {
+	return []route{
+		{http.MethodGet, configURL, h.Get, false, getConfigOperation(false)},
+		{http.MethodPost, configURL, h.Post, false, writeConfigOperation(http.MethodPost, false)},
+		{http.MethodPut, configURL, h.Put, false, writeConfigOperation(http.MethodPut, false)},
+		{http.MethodDelete, configURL, h.Delete, false, deleteConfigOperation(false)},
+
+		{http.MethodGet, servicesURL, h.ListServices, false, listServicesOperation()},
+		{http.MethodGet, serviceConfigURL, h.Get, false, getConfigOperation(true)},
+		{http.MethodPost, serviceConfigURL, h.Post, false, writeConfigOperation(http.MethodPost, true)},
+		{http.MethodPut, serviceConfigURL, h.Put, false, writeConfigOperation(http.MethodPut, true)},
+		{http.MethodDelete, serviceConfigURL, h.Delete, false, deleteConfigOperation(true)},
+		{http.MethodGet, serviceVersionsURL, h.ListVersions, false, listVersionsOperation()},
+		{http.MethodGet, serviceVersionURL, h.Get, false, getVersionOperation()},
+		{http.MethodDelete, serviceVersionURL, h.Delete, false, deleteVersionOperation()},
+
+		{http.MethodGet, exportURL, h.Export, false, exportOperation()},
+		{http.MethodPost, importURL, h.Import, false, importOperation()},
+
+		{http.MethodGet, livenessURL, h.Liveness, true, healthOperation("getLiveness", "Liveness probe")},
+		{http.MethodGet, readyURL, h.Readiness, true, healthOperation("getReadiness", "Readiness probe")},
+		{http.MethodGet, openapiURL, h.OpenAPI, true, openapiOperation()},
 	}
 }
 
 // Register function
 func (h *AppHandlers) Register(router *httprouter.Router) {
-	router.HandlerFunc(http.MethodGet, configURL, h.Get)
-	router.HandlerFunc(http.MethodPost, configURL, h.Post)
-	router.HandlerFunc(http.MethodPut, configURL, h.Put)
-	router.HandlerFunc(http.MethodDelete, configURL, h.Delete)
+	for _, rt := range h.routes() {
+		if rt.public {
+			router.HandlerFunc(rt.method, rt.path, h.instrument(rt.path, rt.handler))
+			continue
+		}
+
+		h.handle(router, rt.method, rt.path, rt.handler)
+	}
+}
+
+// SetReady function
+func (h *AppHandlers) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Liveness function
+func (h *AppHandlers) Liveness(w http.ResponseWriter, r *http.Request) {
+	h.writeStatus(w, r, http.StatusOK, "ok")
+}
+
+// Readiness function
+func (h *AppHandlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		// Error 503
+		h.writeStatus(w, r, http.StatusServiceUnavailable, "shutting down")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), READINESS_TIMEOUT)
+	defer cancel()
+
+	if err := h.Storage.Ping(ctx); err != nil {
+		h.LogInfoRequestDetails("storage backend is not available", err, r)
+		// Error 503
+		h.writeStatus(w, r, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+
+	h.writeStatus(w, r, http.StatusOK, "ok")
 }
 
 // Get function
 func (h *AppHandlers) Get(w http.ResponseWriter, r *http.Request) {
 	service, version, err := h.getServiceAndVersion(r)
 	if err != nil {
-		// Error 400
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, err)
 		return
 	}
 
-	result, err := h.Storage.Read(service, version)
-	if err != nil {
-		switch {
-		case errors.Is(err, common.ErrNotFound):
-			// Error 404
-			w.WriteHeader(http.StatusNotFound)
-		default:
-			// Error 500
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+	h.annotate(r, service, version)
 
+	if !h.allowService(w, r, service) {
+		return
+	}
+
+	revealSecrets := auth.FromContext(r.Context()).Has(auth.PERMISSION_READ_SECRETS)
+
+	markSecrets, err := getSecretsParam(r)
+	if err == nil && markSecrets && !revealSecrets {
+		err = common.ErrForbidden
+	}
+	if err != nil {
+		h.writeError(w, r, err)
 		h.LogInfoRequestDetails("GET request aborted with error", err, r)
+		return
+	}
 
+	var result *common.ConfigRecord
+	if markSecrets {
+		result, err = h.Storage.ReadMarked(r.Context(), service, version)
+	} else {
+		result, err = h.Storage.Read(r.Context(), service, version, revealSecrets)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		h.LogInfoRequestDetails("GET request aborted with error", err, r)
 		return
 	}
 
+	// Метрика учитывается только для существующих сервисов, чтобы
+	// запросы с произвольными именами не создавали новых рядов
+	if r.Header.Get(common.REFRESH_HEADER) != common.EMPTY_STRING {
+		h.Metrics.RefreshPoll(service)
+	}
+
+	h.annotate(r, service, result.Version)
+	h.setConfigVersion(w, &result.VersionInfo)
 	h.setContentTypeJSON(w)
-	if _, err = w.Write(result); err != nil {
+	if _, err = w.Write(result.Data); err != nil {
 		h.LogDebugRequestDetails("http.ResponseWriter was called with an error", err, r)
 	}
-
-	h.LogRequest("GET request completed", r)
 }
 
 // Post function
 func (h *AppHandlers) Post(w http.ResponseWriter, r *http.Request) {
-	postData := &common.RequestData{}
-
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(postData); err != nil {
+	postData, err := h.decodeRequestData(w, r)
+	if err != nil {
 		h.LogInfoRequestDetails("POST request aborted with error", err, r)
-		// Error 400
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	if err := h.Storage.Create(postData); err != nil {
-		switch {
-		case errors.Is(err, common.ErrNotValidJsonData):
-			// Error 400
-			w.WriteHeader(http.StatusBadRequest)
-		case errors.Is(err, common.ErrAlreadyCreated):
-			// Error 403
-			w.WriteHeader(http.StatusForbidden)
-		default:
-			// Error 500
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		return
+	}
 
+	h.LogDebugPayload("POST request payload", postData, r)
+
+	h.annotate(r, postData.Service, 0)
+
+	if !h.allowService(w, r, postData.Service) {
+		return
+	}
+
+	refund, ok := h.reserveVersion(w, r, postData.Service)
+	if !ok {
+		return
+	}
+
+	info, err := h.Storage.Create(r.Context(), postData)
+	if err != nil {
+		refund()
+		h.writeError(w, r, err)
 		h.LogInfoRequestDetails("POST request aborted with error", err, r)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-	h.LogRequest("POST request completed", r)
+	h.annotate(r, postData.Service, info.Version)
+	h.writeVersionInfo(w, r, info)
 }
 
 // Put function
 func (h *AppHandlers) Put(w http.ResponseWriter, r *http.Request) {
-	postData := &common.RequestData{}
+	postData, err := h.decodeRequestData(w, r)
+	if err != nil {
+		h.LogInfoRequestDetails("PUT request aborted with error", err, r)
+		return
+	}
 
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(postData); err != nil {
-		h.LogInfoRequestDetails("GET request aborted with error", err, r)
-		// Error 400
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	if err := h.Storage.Update(postData); err != nil {
-		switch {
-		case errors.Is(err, common.ErrNotValidJsonData):
-			// Error 400
-			w.WriteHeader(http.StatusBadRequest)
-		case errors.Is(err, common.ErrServiceNotFound):
-			// Error 404
-			w.WriteHeader(http.StatusNotFound)
-		default:
-			// Error 500
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+	h.LogDebugPayload("PUT request payload", postData, r)
+
+	// Новая версия создается, только если последняя версия не изменилась
+	postData.IfMatch = r.Header.Get("If-Match")
+
+	h.annotate(r, postData.Service, 0)
+
+	if !h.allowService(w, r, postData.Service) {
+		return
+	}
+
+	refund, ok := h.reserveVersion(w, r, postData.Service)
+	if !ok {
+		return
+	}
 
+	info, err := h.Storage.Update(r.Context(), postData)
+	if err != nil {
+		refund()
+		h.writeError(w, r, err)
 		h.LogInfoRequestDetails("PUT request aborted with error", err, r)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	h.LogRequest("PUT request completed", r)
+	h.annotate(r, postData.Service, info.Version)
+	h.writeVersionInfo(w, r, info)
 }
 
 // Delete function
 func (h *AppHandlers) Delete(w http.ResponseWriter, r *http.Request) {
 	service, version, err := h.getServiceAndVersion(r)
 	if err != nil {
-		// Error 400
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	if err := h.Storage.Delete(service, version); err != nil {
-		switch {
-		case errors.Is(err, common.ErrConfigIsUsed):
-			// Error 403
-			w.WriteHeader(http.StatusForbidden)
-		case errors.Is(err, common.ErrServiceNotFound):
-			// Error 404
-			w.WriteHeader(http.StatusNotFound)
-		default:
-			// Error 500
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.annotate(r, service, version)
 
+	if !h.allowService(w, r, service) {
+		return
+	}
+
+	if err := h.Storage.Delete(r.Context(), service, version); err != nil {
+		h.writeError(w, r, err)
 		h.LogInfoRequestDetails("DELETE request aborted with error", err, r)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	h.LogRequest("DELETE request completed", r)
-}
-
-// LogRequest function
-func (h *AppHandlers) LogRequest(msg string, r *http.Request) {
-	h.Log.Infow(msg,
-		"remote_addr", r.RemoteAddr,
-		"request_uri", r.RequestURI,
-	)
 }
 
 // LogInfoRequestDetails function
 func (h *AppHandlers) LogInfoRequestDetails(msg string, err error, r *http.Request) {
-	h.Log.Infow(msg,
+	logging.FromContext(r.Context()).Infow(msg,
 		"error", err,
-		"reamote_addr", r.RemoteAddr,
-		"request_uri", r.RequestURI,
+		"remote_addr", r.RemoteAddr,
+		"request_uri", h.Redactor.URI(r.RequestURI),
 	)
 }
 
-// LogDebugwRequestDetails function
+// LogDebugRequestDetails function
 func (h *AppHandlers) LogDebugRequestDetails(msg string, err error, r *http.Request) {
-	h.Log.Debugw(msg,
+	logging.FromContext(r.Context()).Debugw(msg,
 		"error", err,
-		"reamote_addr", r.RemoteAddr,
-		"request_uri", r.RequestURI,
+		"remote_addr", r.RemoteAddr,
+		"request_uri", h.Redactor.URI(r.RequestURI),
+	)
+}
+
+// LogDebugPayload function
+func (h *AppHandlers) LogDebugPayload(msg string, data *common.RequestData, r *http.Request) {
+	logging.FromContext(r.Context()).Debugw(msg,
+		"service", data.Service,
+		"data", h.Redactor.Payload(data.Data),
+		"request_uri", h.Redactor.URI(r.RequestURI),
 	)
 }
+
+// ListServices function
+func (h *AppHandlers) ListServices(w http.ResponseWriter, r *http.Request) {
+	services, err := h.Storage.List(r.Context())
+	if err != nil {
+		h.writeError(w, r, err)
+		h.LogInfoRequestDetails("GET request aborted with error", err, r)
+		return
+	}
+
+	sort.Strings(services)
+
+	if services == nil {
+		services = []string{}
+	}
+
+	h.writeJSON(w, r, http.StatusOK, &servicesList{Services: services})
+}
+
+// ListVersions function
+func (h *AppHandlers) ListVersions(w http.ResponseWriter, r *http.Request) {
+	service, _, err := h.getServiceAndVersion(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.annotate(r, service, 0)
+
+	if !h.allowService(w, r, service) {
+		return
+	}
+
+	versions, err := h.Storage.Versions(r.Context(), service)
+	if err != nil {
+		h.writeError(w, r, err)
+		h.LogInfoRequestDetails("GET request aborted with error", err, r)
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, &versionsList{Service: service, Versions: versions})
+}
+
+// Export function
+//
+// Выгружает все версии конфигов всех сервисов в архив NDJSON.
+// Архив передается по мере чтения из хранилища, поэтому при ошибке
+// после начала передачи соединение прерывается.
+func (h *AppHandlers) Export(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	aw := archive.NewWriter(w)
+	count := 0
+
+	start := func() error {
+		w.Header().Set("Content-Type", archive.CONTENT_TYPE)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="configs-%s.ndjson"`,
+			time.Now().UTC().Format("20060102-150405")))
+
+		return aw.WriteHeader()
+	}
+
+	err := h.Storage.Export(r.Context(), func(record *common.ConfigRecord) error {
+		if count == 0 {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		count++
+
+		return aw.Write(record)
+	})
+	if err == nil && count == 0 {
+		err = start()
+	}
+	if err == nil {
+		err = aw.Flush()
+	}
+
+	if err != nil {
+		h.LogInfoRequestDetails("export aborted with error", err, r)
+
+		if count == 0 {
+			h.writeError(w, r, err)
+			return
+		}
+		h.abort(w, r, err)
+	}
+
+	logging.FromContext(r.Context()).Infow("configs exported", "versions", count)
+}
+
+// Import function
+//
+// Загружает архив, полученный при выгрузке. Параметр mode задает
+// режим загрузки merge или replace, параметр preserve_versions -
+// сохранение номеров версий из архива, параметр allow_empty разрешает
+// замену всех сервисов пустым архивом. Загрузка не атомарна: при ошибке
+// сервисы, загруженные до нее, остаются замененными.
+func (h *AppHandlers) Import(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	params, err := h.getImportParams(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if maxSize := h.Limits.MaxImportSize(); maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	records, err := archive.ReadAll(r.Body)
+	if err != nil {
+		if bodyTooLarge(r.Body) {
+			h.Limits.RejectBodySize()
+			err = common.ErrBodyTooLarge
+		}
+
+		h.writeError(w, r, err)
+		h.LogInfoRequestDetails("import aborted with error", err, r)
+		return
+	}
+
+	result, err := h.Storage.Import(r.Context(), records, params)
+	if err != nil {
+		h.writeError(w, r, err)
+		h.LogInfoRequestDetails("import aborted with error", err, r)
+		return
+	}
+
+	logging.FromContext(r.Context()).Infow("configs imported",
+		"replace", params.Replace,
+		"preserve_versions", params.PreserveVersions,
+		"services", len(result.Services),
+		"imported", result.Imported,
+		"skipped", result.Skipped,
+		"deleted", result.Deleted,
+	)
+
+	h.writeJSON(w, r, http.StatusOK, result)
+}
+
+// getSecretsParam function
+//
+// Параметр secrets=marked запрашивает открытые секретные значения
+// в маркерах {"$secret": value}.
+func getSecretsParam(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get(common.SECRETS_PARAM) {
+	case common.EMPTY_STRING:
+		return false, nil
+	case common.SECRETS_MARKED:
+		return true, nil
+	default:
+		return false, common.NewValidationError(common.ErrInvalidRequest, common.SECRETS_PARAM, "must be "+common.SECRETS_MARKED)
+	}
+}
+
+// getImportParams function
+func (h *AppHandlers) getImportParams(r *http.Request) (*common.ImportParams, error) {
+	query := r.URL.Query()
+
+	// По умолчанию номера версий сохраняются, тогда повторная
+	// загрузка того же архива не создает новых версий
+	params := &common.ImportParams{PreserveVersions: true}
+
+	switch query.Get("mode") {
+	case common.EMPTY_STRING, IMPORT_MERGE:
+	case IMPORT_REPLACE:
+		params.Replace = true
+	default:
+		return nil, common.NewValidationError(common.ErrInvalidRequest, "mode", "must be merge or replace")
+	}
+
+	if raw := query.Get("preserve_versions"); raw != common.EMPTY_STRING {
+		var err error
+		if params.PreserveVersions, err = strconv.ParseBool(raw); err != nil {
+			return nil, common.NewValidationError(common.ErrInvalidRequest, "preserve_versions", "must be true or false")
+		}
+	}
+
+	if raw := query.Get("allow_empty"); raw != common.EMPTY_STRING {
+		var err error
+		if params.AllowEmpty, err = strconv.ParseBool(raw); err != nil {
+			return nil, common.NewValidationError(common.ErrInvalidRequest, "allow_empty", "must be true or false")
+		}
+	}
+
+	return params, nil
+}
+
+// requireAdmin function
+func (h *AppHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if auth.FromContext(r.Context()).Has(auth.PERMISSION_ADMIN) {
+		return true
+	}
+
+	h.LogInfoRequestDetails("request aborted with error", common.ErrForbidden, r)
+	h.writeError(w, r, common.ErrForbidden)
+
+	return false
+}
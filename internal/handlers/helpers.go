@@ -1,9 +1,21 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/auth"
 	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/ratelimit"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
 )
 
 // setContentTypeJSON function
@@ -11,18 +23,213 @@ func (h *AppHandlers) setContentTypeJSON(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
+// setConfigVersion function
+func (h *AppHandlers) setConfigVersion(w http.ResponseWriter, info *common.VersionInfo) {
+	w.Header().Set(common.VERSION_HEADER, strconv.Itoa(info.Version))
+	w.Header().Set("ETag", info.ETag)
+}
+
+// writeVersionInfo function
+//
+// Ответ на создание новой версии конфига: 201 Created, ссылка
+// на созданную версию в заголовке Location и сведения о версии в теле.
+func (h *AppHandlers) writeVersionInfo(w http.ResponseWriter, r *http.Request, info *common.VersionInfo) {
+	h.setConfigVersion(w, info)
+	w.Header().Set("Location", versionLocation(r, info))
+
+	h.writeJSON(w, r, http.StatusCreated, info)
+}
+
+// writeJSON function
+func (h *AppHandlers) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	h.setContentTypeJSON(w)
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.LogDebugRequestDetails("http.ResponseWriter was called with an error", err, r)
+	}
+}
+
+// writeStatus function
+func (h *AppHandlers) writeStatus(w http.ResponseWriter, r *http.Request, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(text)); err != nil {
+		logging.FromContext(r.Context()).Debugw("http.ResponseWriter was called with an error", "error", err)
+	}
+}
+
 // getServiceAndVersion function
+//
+// Имя сервиса и номер версии берутся из пути запроса для маршрутов API v1,
+// или из параметров service и version для маршрута /config.
 func (h *AppHandlers) getServiceAndVersion(r *http.Request) (string, int, error) {
-	requestQuery := r.URL.Query()
+	params := httprouter.ParamsFromContext(r.Context())
+
+	service, rawVersion := params.ByName("service"), params.ByName("version")
+	minVersion := 1
+
+	if !isResourceRoute(r) {
+		requestQuery := r.URL.Query()
+		service, rawVersion = requestQuery.Get("service"), requestQuery.Get("version")
+
+		// Для совместимости version=0 в параметрах запроса
+		// также означает последнюю версию конфига
+		minVersion = 0
+	}
 
-	service := requestQuery.Get("service")
 	if service == common.EMPTY_STRING {
-		return common.EMPTY_STRING, 0, common.ErrEmptyServiceName
+		return common.EMPTY_STRING, 0, common.NewValidationError(common.ErrEmptyServiceName, "service", "must not be empty")
 	}
 
-	// Если параметр version не задан, или это не число,
-	// тогда version = 0, это значит выбрать последнюю версию конфига
-	version, _ := strconv.Atoi(requestQuery.Get("version"))
+	// Если номер версии не задан, тогда version = 0,
+	// это значит выбрать последнюю версию конфига
+	if rawVersion == common.EMPTY_STRING {
+		return service, 0, nil
+	}
+
+	version, err := strconv.Atoi(rawVersion)
+	if err != nil || version < minVersion {
+		return common.EMPTY_STRING, 0, common.NewValidationError(common.ErrInvalidVersion, "version", "must be a positive integer")
+	}
 
 	return service, version, nil
 }
+
+// isResourceRoute function
+func isResourceRoute(r *http.Request) bool {
+	return httprouter.ParamsFromContext(r.Context()).ByName("service") != common.EMPTY_STRING
+}
+
+// versionLocation function
+func versionLocation(r *http.Request, info *common.VersionInfo) string {
+	if isResourceRoute(r) {
+		return fmt.Sprintf("/v1/services/%s/versions/%d", url.PathEscape(info.Service), info.Version)
+	}
+
+	location := url.Values{}
+	location.Set("service", info.Service)
+	location.Set("version", strconv.Itoa(info.Version))
+
+	return configURL + "?" + location.Encode()
+}
+
+// allowService function
+//
+// Проверяет ограничение частоты запросов к сервису.
+func (h *AppHandlers) allowService(w http.ResponseWriter, r *http.Request, service string) bool {
+	delay, ok := h.Limits.AllowService(service)
+	if !ok {
+		h.LogInfoRequestDetails("request aborted with error", common.ErrRateLimited, r)
+		h.tooManyRequests(w, r, delay)
+	}
+
+	return ok
+}
+
+// reserveVersion function
+//
+// Проверяет ограничение количества новых версий конфига сервиса
+// в минуту. Резерв нужно вернуть функцией refund, если версия
+// не была создана.
+func (h *AppHandlers) reserveVersion(w http.ResponseWriter, r *http.Request, service string) (func(), bool) {
+	delay, refund, ok := h.Limits.ReserveVersion(service)
+	if !ok {
+		h.LogInfoRequestDetails("request aborted with error", common.ErrRateLimited, r)
+		h.tooManyRequests(w, r, delay)
+	}
+
+	return refund, ok
+}
+
+// tooManyRequests function
+func (h *AppHandlers) tooManyRequests(w http.ResponseWriter, r *http.Request, delay time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfter(delay)))
+	h.writeError(w, r, common.ErrRateLimited)
+}
+
+// clientKey function
+func (h *AppHandlers) clientKey(r *http.Request) string {
+	if identity := auth.FromContext(r.Context()); identity != nil && identity.Name != auth.ANONYMOUS {
+		return identity.Name
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// bodyTooLarge function
+//
+// Превышен ли размер тела запроса, ограниченного http.MaxBytesReader.
+// Ошибка превышения сохраняется и возвращается при следующем чтении.
+func bodyTooLarge(body io.Reader) bool {
+	_, err := body.Read(make([]byte, 1))
+
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// decodeRequestData function
+func (h *AppHandlers) decodeRequestData(w http.ResponseWriter, r *http.Request) (*common.RequestData, error) {
+	if maxSize := h.Limits.MaxBodySize(); maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	postData := &common.RequestData{}
+
+	// В маршрутах API v1 имя сервиса передается в пути,
+	// а тело запроса целиком содержит конфиг
+	var target interface{} = postData
+	if isResourceRoute(r) {
+		postData.Service = httprouter.ParamsFromContext(r.Context()).ByName("service")
+		target = &postData.Data
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(target); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.Limits.RejectBodySize()
+			err = common.ErrBodyTooLarge
+		} else {
+			err = fmt.Errorf("%w: %v", common.ErrInvalidRequest, err)
+		}
+
+		h.writeError(w, r, err)
+		return nil, err
+	}
+
+	if err := h.validateRequestData(postData); err != nil {
+		h.writeError(w, r, err)
+		return nil, err
+	}
+
+	return postData, nil
+}
+
+// validateRequestData function
+func (h *AppHandlers) validateRequestData(data *common.RequestData) error {
+	result := &common.ValidationError{}
+
+	if data.Service == common.EMPTY_STRING {
+		result.Err = common.ErrEmptyServiceName
+		result.Params = append(result.Params, common.InvalidParam{Name: "service", Reason: "must not be empty"})
+	}
+
+	if len(data.Data) == 0 || string(data.Data) == "null" {
+		if result.Err == nil {
+			result.Err = common.ErrNotValidJsonData
+		}
+		result.Params = append(result.Params, common.InvalidParam{Name: "data", Reason: "must be a JSON value"})
+	}
+
+	if len(result.Params) > 0 {
+		return result
+	}
+
+	return nil
+}
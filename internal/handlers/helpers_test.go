@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"go-cloud-camp/internal/archive"
+	"go-cloud-camp/internal/common"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// routeRequest function
+//
+// Запрос с параметрами пути, как после разбора маршрута httprouter.
+func routeRequest(target string, params httprouter.Params) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if params == nil {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, params))
+}
+
+func TestGetServiceAndVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       *http.Request
+		service string
+		version int
+		err     error
+	}{
+		{"query", routeRequest("/config?service=svc&version=3", nil), "svc", 3, nil},
+		{"query latest", routeRequest("/config?service=svc", nil), "svc", 0, nil},
+		{"query zero", routeRequest("/config?service=svc&version=0", nil), "svc", 0, nil},
+		{"query negative", routeRequest("/config?service=svc&version=-1", nil), "", 0, common.ErrInvalidVersion},
+		{"query not a number", routeRequest("/config?service=svc&version=abc", nil), "", 0, common.ErrInvalidVersion},
+		{"query no service", routeRequest("/config?version=1", nil), "", 0, common.ErrEmptyServiceName},
+		{"path", routeRequest("/v1/services/svc/versions/2", httprouter.Params{{Key: "service", Value: "svc"}, {Key: "version", Value: "2"}}), "svc", 2, nil},
+		{"path latest", routeRequest("/v1/services/svc/config", httprouter.Params{{Key: "service", Value: "svc"}}), "svc", 0, nil},
+		{"path zero", routeRequest("/v1/services/svc/versions/0", httprouter.Params{{Key: "service", Value: "svc"}, {Key: "version", Value: "0"}}), "", 0, common.ErrInvalidVersion},
+	}
+
+	h := &AppHandlers{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, version, err := h.getServiceAndVersion(tt.r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if service != tt.service || version != tt.version {
+				t.Fatalf("got %q version %d, want %q version %d", service, version, tt.service, tt.version)
+			}
+		})
+	}
+}
+
+func TestVersionLocation(t *testing.T) {
+	info := &common.VersionInfo{Service: "my svc", Version: 4}
+
+	if got := versionLocation(routeRequest("/config", nil), info); got != "/config?service=my+svc&version=4" {
+		t.Fatalf("location = %q", got)
+	}
+
+	r := routeRequest("/v1/services/my%20svc/config", httprouter.Params{{Key: "service", Value: "my svc"}})
+	if got := versionLocation(r, info); got != "/v1/services/my%20svc/versions/4" {
+		t.Fatalf("location = %q", got)
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	header := `{"format":"` + archive.FORMAT + `","version":1}` + "\n"
+	record := func(version int) string {
+		return `{"service":"svc","version":` + strconv.Itoa(version) + `,"data":{"a":"` + strings.Repeat("x", 100) + `"}}` + "\n"
+	}
+
+	large := header
+	for i := 1; i <= 20; i++ {
+		large += record(i)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		tooBig  bool
+		invalid bool
+	}{
+		{"within limit", header + record(1), 1024, false, false},
+		{"over limit", large, 1024, true, true},
+		{"invalid archive", header + "{", 1024, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/import", strings.NewReader(tt.body))
+			body := http.MaxBytesReader(httptest.NewRecorder(), r.Body, tt.limit)
+
+			_, err := archive.ReadAll(body)
+			if (err != nil) != tt.invalid {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if err != nil && bodyTooLarge(body) != tt.tooBig {
+				t.Fatalf("bodyTooLarge() = %v, want %v", !tt.tooBig, tt.tooBig)
+			}
+		})
+	}
+}
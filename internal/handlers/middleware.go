@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"go-cloud-camp/internal/auth"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/logging"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// requestInfo struct
+//
+// Данные запроса, которые заполняются обработчиками
+// и выводятся в журнал доступа.
+type requestInfo struct {
+	actor   string
+	service string
+	version int
+
+	// Ошибка, из-за которой прервана передача ответа
+	err error
+}
+
+type requestInfoKey struct{}
+
+// handle function
+//
+// Регистрирует обработчик маршрута вместе с общими обработчиками
+// (журнал доступа, метрики, аутентификация, ограничение частоты запросов).
+func (h *AppHandlers) handle(router *httprouter.Router, method string, route string, handler http.HandlerFunc) {
+	handler = h.accessLog(route, h.instrument(route, h.authenticate(h.limitClient(handler))))
+	router.Handler(method, route, otelhttp.NewHandler(handler, method+" "+route))
+}
+
+// accessLog function
+//
+// Назначает запросу идентификатор (или использует переданный клиентом
+// в заголовке X-Request-ID), добавляет в контекст запроса логгер с этим
+// идентификатором и записывает в журнал одну строку по завершении запроса.
+func (h *AppHandlers) accessLog(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := h.requestID(r)
+		w.Header().Set(REQUEST_ID_HEADER, requestID)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request_id", requestID))
+
+		logger := &logging.Logger{SugaredLogger: h.Log.With("request_id", requestID)}
+		info := &requestInfo{}
+
+		ctx := logging.WithContext(r.Context(), logger)
+		ctx = context.WithValue(ctx, requestInfoKey{}, info)
+
+		rec := newResponseRecorder(w)
+
+		// Запрос записывается в журнал и при прерывании обработчика
+		completed := false
+		defer func() {
+			fields := []interface{}{
+				"method", r.Method,
+				"route", route,
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+				"remote_addr", r.RemoteAddr,
+				"actor", info.actor,
+				"service", info.service,
+				"version", info.version,
+			}
+
+			if completed {
+				logger.Infow("request completed", fields...)
+				return
+			}
+
+			if info.err != nil {
+				fields = append(fields, "error", info.err)
+			}
+			logger.Errorw("request aborted", fields...)
+		}()
+
+		next(rec, r.WithContext(ctx))
+		completed = true
+	}
+}
+
+// abort function
+//
+// Прерывает передачу ответа, которая уже началась. Для журнала доступа
+// и метрик сохраняются ошибка и статус 500, хотя клиент уже получил
+// статус ответа.
+func (h *AppHandlers) abort(w http.ResponseWriter, r *http.Request, err error) {
+	if rec, ok := w.(*responseRecorder); ok {
+		rec.status = http.StatusInternalServerError
+	}
+
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.err = err
+	}
+
+	panic(http.ErrAbortHandler)
+}
+
+// instrument function
+func (h *AppHandlers) instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rec, ok := w.(*responseRecorder)
+		if !ok {
+			rec = newResponseRecorder(w)
+		}
+
+		// Метрики учитывают и прерванные запросы
+		defer func() {
+			h.Metrics.ObserveRequest(route, r.Method, rec.status, time.Since(start))
+		}()
+
+		next(rec, r)
+	}
+}
+
+// authenticate function
+func (h *AppHandlers) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, err := h.Auth.Identify(r)
+		if err != nil {
+			h.LogInfoRequestDetails("request aborted with error", err, r)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			h.writeError(w, r, err)
+			return
+		}
+
+		if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+			info.actor = identity.Name
+		}
+
+		next(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	}
+}
+
+// limitClient function
+func (h *AppHandlers) limitClient(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if delay, ok := h.Limits.AllowClient(h.clientKey(r)); !ok {
+			h.LogInfoRequestDetails("request aborted with error", common.ErrRateLimited, r)
+			h.tooManyRequests(w, r, delay)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// annotate function
+//
+// Сохраняет имя сервиса и номер версии для журнала доступа.
+func (h *AppHandlers) annotate(r *http.Request, service string, version int) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.service = service
+		info.version = version
+	}
+}
+
+// requestID function
+func (h *AppHandlers) requestID(r *http.Request) string {
+	return common.RequestID(r.Header.Get(REQUEST_ID_HEADER))
+}
+
+// responseRecorder struct
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// newResponseRecorder function
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader function
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+// Write function
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
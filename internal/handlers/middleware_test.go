@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLogRequestID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	h := &AppHandlers{Log: &logging.Logger{SugaredLogger: zap.New(core).Sugar()}}
+
+	handler := h.accessLog("/config", func(w http.ResponseWriter, r *http.Request) {
+		h.annotate(r, "svc", 2)
+		logging.FromContext(r.Context()).Infow("handled")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"client id", "abc-123", true},
+		{"empty", "", false},
+		{"with space", "abc 123", false},
+		{"too long", strings.Repeat("x", common.MAX_REQUEST_ID_LENGTH+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			r := httptest.NewRequest(http.MethodGet, "/config?service=svc", nil)
+			if tt.header != "" {
+				r.Header.Set(REQUEST_ID_HEADER, tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+
+			id := rec.Header().Get(REQUEST_ID_HEADER)
+			if tt.keep && id != tt.header {
+				t.Fatalf("request id = %q, want %q", id, tt.header)
+			}
+			if !tt.keep && (id == tt.header || common.RequestID(id) != id) {
+				t.Fatalf("request id = %q, want a generated id", id)
+			}
+
+			// Логгер обработчика и журнал доступа используют один идентификатор
+			entries := logs.TakeAll()
+			if len(entries) != 2 {
+				t.Fatalf("logged %d entries, want 2", len(entries))
+			}
+			for _, entry := range entries {
+				if got := entry.ContextMap()["request_id"]; got != id {
+					t.Fatalf("%q logged with request_id %v, want %q", entry.Message, got, id)
+				}
+			}
+
+			fields := entries[1].ContextMap()
+			if entries[1].Message != "request completed" || fields["status"] != int64(http.StatusCreated) ||
+				fields["service"] != "svc" || fields["version"] != int64(2) {
+				t.Fatalf("access log %q fields = %v", entries[1].Message, fields)
+			}
+		})
+	}
+}
+
+func TestAbortedRequestIsLogged(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	h := &AppHandlers{
+		Log:     &logging.Logger{SugaredLogger: zap.New(core).Sugar()},
+		Metrics: metrics.Create(),
+	}
+
+	streamErr := errors.New("storage failed")
+
+	// Ошибка после начала передачи ответа, как при выгрузке архива
+	handler := h.accessLog("/stream", h.instrument("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial\n"))
+		h.abort(w, r, streamErr)
+	}))
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("aborted response is read without error")
+	}
+
+	entries := logs.FilterMessage("request aborted").All()
+	if len(entries) != 1 {
+		t.Fatalf("aborted request is logged %d times", len(entries))
+	}
+
+	fields := entries[0].ContextMap()
+	if fields["status"] != int64(http.StatusInternalServerError) || fields["error"] != streamErr.Error() {
+		t.Fatalf("access log fields = %v", fields)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), `code="500",method="GET",route="/stream"`) {
+		t.Fatalf("aborted request is not counted:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-cloud-camp/internal/archive"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/openapi"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const API_VERSION = "1.0.0"
+
+// Ответы с ошибками по кодам статуса HTTP
+var errorResponses = map[int]string{
+	http.StatusBadRequest:            "BadRequest",
+	http.StatusUnauthorized:          "Unauthorized",
+	http.StatusForbidden:             "Forbidden",
+	http.StatusNotFound:              "NotFound",
+	http.StatusConflict:              "Conflict",
+	http.StatusPreconditionFailed:    "PreconditionFailed",
+	http.StatusRequestEntityTooLarge: "PayloadTooLarge",
+	http.StatusTooManyRequests:       "TooManyRequests",
+	http.StatusInternalServerError:   "InternalError",
+}
+
+// Spec function
+//
+// Строит спецификацию OpenAPI по таблице маршрутов сервера.
+func (h *AppHandlers) Spec() *openapi.Document {
+	doc := openapi.Create(openapi.Info{
+		Title:       "Config Server API",
+		Description: "Versioned JSON configuration storage for services. Every response carries the X-Request-ID header.",
+		Version:     API_VERSION,
+	})
+
+	for _, rt := range h.routes() {
+		doc.AddOperation(rt.method, rt.path, rt.operation)
+	}
+
+	doc.Security = []openapi.SecurityRequirement{{"bearerAuth": {}}, {}}
+	doc.Components.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "API token from the auth.tokens section of the server config. Requests without a token get anonymous permissions.",
+	}
+
+	addSchemas(doc)
+	addParameters(doc)
+
+	for status, name := range errorResponses {
+		doc.Components.Responses[name] = problemResponse(status)
+	}
+
+	return doc
+}
+
+// OpenAPI function
+func (h *AppHandlers) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(h.Spec())
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.setContentTypeJSON(w)
+	if _, err = w.Write(body); err != nil {
+		h.LogDebugRequestDetails("http.ResponseWriter was called with an error", err, r)
+	}
+}
+
+// getConfigOperation function
+func getConfigOperation(resource bool) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: "getConfig",
+		Summary:     "Read the latest or the requested config version",
+		Tags:        []string{"legacy"},
+		Parameters: []*openapi.Parameter{
+			openapi.ParameterRef("ServiceQuery"),
+			openapi.ParameterRef("VersionQuery"),
+			openapi.ParameterRef("SecretsQuery"),
+			openapi.ParameterRef("RefreshHeader"),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": configResponse(),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests),
+	}
+
+	if resource {
+		op.OperationID = "getServiceConfig"
+		op.Summary = "Read the latest config version"
+		op.Tags = []string{"configs"}
+		op.Parameters = []*openapi.Parameter{
+			openapi.ParameterRef("ServicePath"),
+			openapi.ParameterRef("SecretsQuery"),
+			openapi.ParameterRef("RefreshHeader"),
+		}
+	}
+
+	return op
+}
+
+// listServicesOperation function
+func listServicesOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "listServices",
+		Summary:     "List services with stored configs",
+		Tags:        []string{"configs"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {
+				Description: "Service names in alphabetical order",
+				Content:     openapi.JSONContent("application/json", openapi.SchemaRef("ServiceList")),
+			},
+		}, http.StatusUnauthorized),
+	}
+}
+
+// listVersionsOperation function
+func listVersionsOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "listServiceConfigVersions",
+		Summary:     "List stored config versions of a service",
+		Tags:        []string{"configs"},
+		Parameters:  []*openapi.Parameter{openapi.ParameterRef("ServicePath")},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {
+				Description: "Config versions in ascending order",
+				Content:     openapi.JSONContent("application/json", openapi.SchemaRef("VersionList")),
+			},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests),
+	}
+}
+
+// getVersionOperation function
+func getVersionOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "getServiceConfigVersion",
+		Summary:     "Read a config version",
+		Tags:        []string{"configs"},
+		Parameters: []*openapi.Parameter{
+			openapi.ParameterRef("ServicePath"),
+			openapi.ParameterRef("VersionPath"),
+			openapi.ParameterRef("SecretsQuery"),
+			openapi.ParameterRef("RefreshHeader"),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": configResponse(),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests),
+	}
+}
+
+// writeConfigOperation function
+func writeConfigOperation(method string, resource bool) *openapi.Operation {
+	op := &openapi.Operation{
+		Tags: []string{"legacy"},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  openapi.JSONContent("application/json", openapi.SchemaRef("ConfigRequest")),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"201": {
+				Description: "Config version created",
+				Headers: map[string]*openapi.Header{
+					"Location":            {Description: "URL of the created config version", Schema: &openapi.Schema{Type: "string"}},
+					common.VERSION_HEADER: {Description: "Created config version", Schema: &openapi.Schema{Type: "integer"}},
+					"ETag":                {Description: "Created config version entity tag", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Content: openapi.JSONContent("application/json", openapi.SchemaRef("VersionInfo")),
+			},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests),
+	}
+
+	if method == http.MethodPost {
+		op.OperationID, op.Summary = "createConfig", "Create the first config version of a service"
+		op.Responses[strconv.Itoa(http.StatusConflict)] = openapi.ResponseRef(errorResponses[http.StatusConflict])
+	} else {
+		op.OperationID, op.Summary = "updateConfig", "Create a new config version of an existing service"
+		op.Parameters = []*openapi.Parameter{openapi.ParameterRef("IfMatchHeader")}
+		op.Responses[strconv.Itoa(http.StatusNotFound)] = openapi.ResponseRef(errorResponses[http.StatusNotFound])
+		op.Responses[strconv.Itoa(http.StatusPreconditionFailed)] = openapi.ResponseRef(errorResponses[http.StatusPreconditionFailed])
+	}
+
+	if resource {
+		op.OperationID = strings.Replace(op.OperationID, "Config", "ServiceConfig", 1)
+		op.Tags = []string{"configs"}
+		op.Parameters = append([]*openapi.Parameter{openapi.ParameterRef("ServicePath")}, op.Parameters...)
+		op.RequestBody.Content = openapi.JSONContent("application/json", openapi.SchemaRef("Config"))
+	}
+
+	return op
+}
+
+// deleteConfigOperation function
+func deleteConfigOperation(resource bool) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: "deleteConfig",
+		Summary:     "Delete a config version, or all versions of a service without version",
+		Tags:        []string{"legacy"},
+		Parameters: []*openapi.Parameter{
+			openapi.ParameterRef("ServiceQuery"),
+			openapi.ParameterRef("VersionQuery"),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {Description: "Config deleted"},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests),
+	}
+
+	if resource {
+		op.OperationID = "deleteServiceConfig"
+		op.Summary = "Delete all config versions of a service"
+		op.Tags = []string{"configs"}
+		op.Parameters = []*openapi.Parameter{openapi.ParameterRef("ServicePath")}
+	}
+
+	return op
+}
+
+// deleteVersionOperation function
+func deleteVersionOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "deleteServiceConfigVersion",
+		Summary:     "Delete a config version",
+		Tags:        []string{"configs"},
+		Parameters: []*openapi.Parameter{
+			openapi.ParameterRef("ServicePath"),
+			openapi.ParameterRef("VersionPath"),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {Description: "Config version deleted"},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests),
+	}
+}
+
+// exportOperation function
+func exportOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "exportConfigs",
+		Summary:     "Export all config versions of all services",
+		Description: "Requires the admin permission. The first line of the archive is the header, every next line is a config version. Secret values stay encrypted.",
+		Tags:        []string{"admin"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {
+				Description: "Archive of config versions",
+				Headers: map[string]*openapi.Header{
+					"Content-Disposition": {Description: "Archive file name", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Content: openapi.JSONContent(archive.CONTENT_TYPE, openapi.SchemaRef("ArchiveRecord")),
+			},
+		}, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests),
+	}
+}
+
+// importOperation function
+func importOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "importConfigs",
+		Summary:     "Import an archive produced by exportConfigs",
+		Description: "Requires the admin permission. Encrypted secret values can be read only with the key file of the exporting server. The import is not atomic: after an error the services imported before it stay replaced, the service that failed is restored to its previous versions.",
+		Tags:        []string{"admin"},
+		Parameters: []*openapi.Parameter{
+			{
+				Name: "mode", In: "query",
+				Description: "merge adds versions to existing configs, replace swaps the config of each archived service for the archived versions and then deletes services missing from the archive",
+				Schema:      &openapi.Schema{Type: "string", Enum: []string{IMPORT_MERGE, IMPORT_REPLACE}},
+			},
+			{
+				Name: "preserve_versions", In: "query",
+				Description: "Keep version numbers from the archive and skip versions that already exist (default true), otherwise number imported versions after the latest one",
+				Schema:      &openapi.Schema{Type: "boolean"},
+			},
+			{
+				Name: "allow_empty", In: "query",
+				Description: "Confirm a replace import of an archive without versions, which deletes every service (default false)",
+				Schema:      &openapi.Schema{Type: "boolean"},
+			},
+		},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  openapi.JSONContent(archive.CONTENT_TYPE, openapi.SchemaRef("ArchiveRecord")),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {
+				Description: "Archive imported",
+				Content:     openapi.JSONContent("application/json", openapi.SchemaRef("ImportResult")),
+			},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests),
+	}
+}
+
+// healthOperation function
+func healthOperation(id string, summary string) *openapi.Operation {
+	text := openapi.JSONContent("text/plain", &openapi.Schema{Type: "string"})
+
+	return &openapi.Operation{
+		OperationID: id,
+		Summary:     summary,
+		Tags:        []string{"health"},
+		Security:    []openapi.SecurityRequirement{{}},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Server is healthy", Content: text},
+			"503": {Description: "Server is not ready to serve requests", Content: text},
+		},
+	}
+}
+
+// openapiOperation function
+func openapiOperation() *openapi.Operation {
+	return &openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "OpenAPI specification of the server",
+		Tags:        []string{"meta"},
+		Security:    []openapi.SecurityRequirement{{}},
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "OpenAPI document",
+				Content:     openapi.JSONContent("application/json", &openapi.Schema{Type: "object"}),
+			},
+		},
+	}
+}
+
+// configResponse function
+func configResponse() *openapi.Response {
+	return &openapi.Response{
+		Description: "Config data. Secret values are redacted unless the caller has the read-secrets permission.",
+		Headers: map[string]*openapi.Header{
+			common.VERSION_HEADER: {Description: "Returned config version", Schema: &openapi.Schema{Type: "integer"}},
+			"ETag":                {Description: "Returned config version entity tag", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Content: openapi.JSONContent("application/json", openapi.SchemaRef("Config")),
+	}
+}
+
+// withErrors function
+//
+// Добавляет к ответам операции ссылки на ответы с ошибками.
+// Внутренняя ошибка сервера возможна для любой операции.
+func withErrors(responses map[string]*openapi.Response, statuses ...int) map[string]*openapi.Response {
+	statuses = append(statuses, http.StatusInternalServerError)
+
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = openapi.ResponseRef(errorResponses[status])
+	}
+
+	return responses
+}
+
+// problemResponse function
+func problemResponse(status int) *openapi.Response {
+	response := &openapi.Response{
+		Description: http.StatusText(status),
+		Content:     openapi.JSONContent(PROBLEM_CONTENT_TYPE, openapi.SchemaRef("Problem")),
+	}
+
+	if status == http.StatusUnauthorized {
+		response.Headers = map[string]*openapi.Header{
+			"WWW-Authenticate": {Schema: &openapi.Schema{Type: "string"}},
+		}
+	}
+
+	if status == http.StatusTooManyRequests {
+		response.Headers = map[string]*openapi.Header{
+			"Retry-After": {Description: "Seconds to wait before retrying", Schema: &openapi.Schema{Type: "integer"}},
+		}
+	}
+
+	return response
+}
+
+// addParameters function
+func addParameters(doc *openapi.Document) {
+	minLatest, minVersion := 0, 1
+
+	doc.Components.Parameters["ServiceQuery"] = &openapi.Parameter{
+		Name: "service", In: "query", Required: true,
+		Description: "Service name",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	doc.Components.Parameters["VersionQuery"] = &openapi.Parameter{
+		Name: "version", In: "query",
+		Description: "Config version, 0 or missing selects the latest version",
+		Schema:      &openapi.Schema{Type: "integer", Minimum: &minLatest},
+	}
+	doc.Components.Parameters["ServicePath"] = &openapi.Parameter{
+		Name: "service", In: "path", Required: true,
+		Description: "Service name",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	doc.Components.Parameters["VersionPath"] = &openapi.Parameter{
+		Name: "version", In: "path", Required: true,
+		Description: "Config version",
+		Schema:      &openapi.Schema{Type: "integer", Minimum: &minVersion},
+	}
+	doc.Components.Parameters["SecretsQuery"] = &openapi.Parameter{
+		Name: common.SECRETS_PARAM, In: "query",
+		Description: "marked returns secret values in {\"$secret\": value} markers, so the config can be written back unchanged. Requires the read-secrets permission",
+		Schema:      &openapi.Schema{Type: "string", Enum: []string{common.SECRETS_MARKED}},
+	}
+	doc.Components.Parameters["IfMatchHeader"] = &openapi.Parameter{
+		Name: "If-Match", In: "header",
+		Description: "ETag of the latest config version, the version is created only if the latest version is unchanged. Config ETags are weak, and unlike the strong comparison of RFC 7232 the value is compared with the weak ETag as an exact string, including the W/ prefix",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	doc.Components.Parameters["RefreshHeader"] = &openapi.Parameter{
+		Name: common.REFRESH_HEADER, In: "header",
+		Description: "Set by clients polling for config changes",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// addSchemas function
+func addSchemas(doc *openapi.Document) {
+	codes := make([]string, 0, len(errorStatus))
+	for code := range errorStatus {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	doc.Components.Schemas["Config"] = &openapi.Schema{
+		Description: "Arbitrary JSON config. Objects of the form {\"$secret\": value} are stored encrypted.",
+	}
+	doc.Components.Schemas["ConfigRequest"] = &openapi.Schema{
+		Type:     "object",
+		Required: []string{"service", "data"},
+		Properties: map[string]*openapi.Schema{
+			"service": {Type: "string"},
+			"data":    openapi.SchemaRef("Config"),
+		},
+	}
+	doc.Components.Schemas["VersionInfo"] = &openapi.Schema{
+		Type:     "object",
+		Required: []string{"service", "version", "createdAt", "etag"},
+		Properties: map[string]*openapi.Schema{
+			"service":   {Type: "string"},
+			"version":   {Type: "integer"},
+			"createdAt": {Type: "string", Format: "date-time"},
+			"etag":      {Type: "string"},
+		},
+	}
+	doc.Components.Schemas["ServiceList"] = &openapi.Schema{
+		Type:     "object",
+		Required: []string{"services"},
+		Properties: map[string]*openapi.Schema{
+			"services": {Type: "array", Items: &openapi.Schema{Type: "string"}},
+		},
+	}
+	doc.Components.Schemas["VersionList"] = &openapi.Schema{
+		Type:     "object",
+		Required: []string{"service", "versions"},
+		Properties: map[string]*openapi.Schema{
+			"service":  {Type: "string"},
+			"versions": {Type: "array", Items: openapi.SchemaRef("VersionInfo")},
+		},
+	}
+	doc.Components.Schemas["ArchiveRecord"] = &openapi.Schema{
+		Type:        "object",
+		Description: "Line of an NDJSON archive, the header line has the format, version and exportedAt properties",
+		Required:    []string{"service", "version", "data"},
+		Properties: map[string]*openapi.Schema{
+			"service":   {Type: "string"},
+			"version":   {Type: "integer"},
+			"createdAt": {Type: "string", Format: "date-time"},
+			"etag":      {Type: "string"},
+			"data":      openapi.SchemaRef("Config"),
+		},
+	}
+	doc.Components.Schemas["ImportResult"] = &openapi.Schema{
+		Type:     "object",
+		Required: []string{"services", "imported", "skipped", "deleted"},
+		Properties: map[string]*openapi.Schema{
+			"services": {Type: "array", Items: &openapi.Schema{Type: "string"}},
+			"imported": {Type: "integer"},
+			"skipped":  {Type: "integer"},
+			"deleted":  {Type: "array", Items: &openapi.Schema{Type: "string"}},
+		},
+	}
+	doc.Components.Schemas["InvalidParam"] = &openapi.Schema{
+		Type:     "object",
+		Required: []string{"name", "reason"},
+		Properties: map[string]*openapi.Schema{
+			"name":   {Type: "string"},
+			"reason": {Type: "string"},
+		},
+	}
+	doc.Components.Schemas["Problem"] = &openapi.Schema{
+		Type:        "object",
+		Description: "RFC 7807 problem details",
+		Required:    []string{"type", "title", "status", "code"},
+		Properties: map[string]*openapi.Schema{
+			"type":           {Type: "string"},
+			"title":          {Type: "string"},
+			"status":         {Type: "integer"},
+			"detail":         {Type: "string"},
+			"instance":       {Type: "string"},
+			"code":           {Type: "string", Enum: codes},
+			"request_id":     {Type: "string"},
+			"invalid_params": {Type: "array", Items: openapi.SchemaRef("InvalidParam")},
+		},
+	}
+}
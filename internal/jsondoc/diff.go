@@ -0,0 +1,126 @@
+package jsondoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Разделитель ключей в пути к значению документа
+const PATH_SEPARATOR = "."
+
+// Change struct
+//
+// Изменение значения по пути из ключей объектов и индексов массивов,
+// например "servers.0.host". Значение nil означает, что значения нет
+// в исходном или новом документе.
+type Change struct {
+	Path string
+	Old  json.RawMessage
+	New  json.RawMessage
+}
+
+// Diff function
+//
+// Изменения значений документа to относительно документа from
+// в порядке путей. Пустые объекты и массивы считаются значениями.
+func Diff(from interface{}, to interface{}) []Change {
+	fromValues, toValues := Flatten(from), Flatten(to)
+
+	paths := make([]string, 0, len(fromValues)+len(toValues))
+	for path := range fromValues {
+		paths = append(paths, path)
+	}
+	for path := range toValues {
+		if _, ok := fromValues[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []Change
+
+	for _, path := range paths {
+		oldValue, oldOk := fromValues[path]
+		newValue, newOk := toValues[path]
+
+		if oldOk && newOk && string(oldValue) == string(newValue) {
+			continue
+		}
+
+		changes = append(changes, Change{Path: path, Old: oldValue, New: newValue})
+	}
+
+	return changes
+}
+
+// Flatten function
+//
+// Значения документа по путям, значения кодируются в JSON.
+func Flatten(doc interface{}) map[string]json.RawMessage {
+	result := map[string]json.RawMessage{}
+	flatten(doc, "", result)
+
+	return result
+}
+
+// flatten function
+func flatten(value interface{}, path string, result map[string]json.RawMessage) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + PATH_SEPARATOR + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			for key, inner := range v {
+				flatten(inner, join(key), result)
+			}
+			return
+		}
+
+	case []interface{}:
+		if len(v) > 0 {
+			for i, inner := range v {
+				flatten(inner, join(strconv.Itoa(i)), result)
+			}
+			return
+		}
+	}
+
+	// Ключи объектов кодируются в порядке сортировки, поэтому
+	// одинаковые значения дают одинаковый результат
+	data, err := Encode(value)
+	if err != nil {
+		data = json.RawMessage("null")
+	}
+	result[path] = data
+}
+
+// FromYAML function
+//
+// Документ JSON возвращается без изменений, документ YAML
+// преобразуется в JSON.
+func FromYAML(data []byte) (json.RawMessage, error) {
+	if json.Valid(data) {
+		return data, nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("document is neither JSON nor YAML: %w", err)
+	}
+
+	result, err := Encode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("document can't be converted to JSON: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// VisitFunc type
+//
+// Вызывается для каждого значения документа вместе с ключом, под которым
+// оно находится в родительском объекте (для элементов массива ключ пустой).
+// Возвращает новое значение и признак замены, замененные значения
+// дальше не обходятся.
+type VisitFunc func(key string, value interface{}) (interface{}, bool, error)
+
+// Decode function
+func Decode(data []byte) (interface{}, error) {
+	var doc interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Encode function
+func Encode(doc interface{}) (json.RawMessage, error) {
+	buf := &bytes.Buffer{}
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// Transform function
+//
+// Обходит документ и возвращает его измененную копию. Если ни одно значение
+// не было заменено, возвращаются исходные данные без повторной сериализации.
+func Transform(data json.RawMessage, fn VisitFunc) (json.RawMessage, bool, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := false
+	if doc, err = Walk(doc, fn, &changed); err != nil {
+		return nil, false, err
+	}
+
+	if !changed {
+		return data, false, nil
+	}
+
+	result, err := Encode(doc)
+
+	return result, err == nil, err
+}
+
+// Walk function
+func Walk(doc interface{}, fn VisitFunc, changed *bool) (interface{}, error) {
+	return walk("", doc, fn, changed)
+}
+
+// walk function
+func walk(key string, value interface{}, fn VisitFunc, changed *bool) (interface{}, error) {
+	result, replaced, err := fn(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if replaced {
+		*changed = true
+		return result, nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, inner := range v {
+			if v[k], err = walk(k, inner, fn, changed); err != nil {
+				return nil, err
+			}
+		}
+
+	case []interface{}:
+		for i, inner := range v {
+			if v[i], err = walk("", inner, fn, changed); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return value, nil
+}
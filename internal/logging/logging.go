@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"context"
 	"go-cloud-camp/internal/config"
 	"sync"
 
@@ -14,6 +15,8 @@ type Logger struct {
 	*zap.SugaredLogger
 }
 
+type contextKey struct{}
+
 // GetLogger function
 func GetLogger(cfg config.LoggingParams) (*Logger, error) {
 	var err error
@@ -33,3 +36,24 @@ func GetLogger(cfg config.LoggingParams) (*Logger, error) {
 
 	return instance, err
 }
+
+// WithContext function
+func WithContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext function
+//
+// Возвращает логгер запроса из контекста, или общий логгер приложения,
+// если в контексте логгера нет.
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return logger
+	}
+
+	if instance != nil {
+		return instance
+	}
+
+	return &Logger{zap.NewNop().Sugar()}
+}
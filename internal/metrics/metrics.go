@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"go-cloud-camp/internal/ratelimit"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const NAMESPACE = "config_server"
+
+// StatsFunc type
+//
+// Возвращает количество версий конфигов по сервисам.
+type StatsFunc func() (map[string]int, error)
+
+// Metrics struct
+type Metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	storageDuration  *prometheus.HistogramVec
+	storageErrors    *prometheus.CounterVec
+	refreshPolls     *prometheus.CounterVec
+	watchSubscribers prometheus.Gauge
+}
+
+// Create function
+func Create() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage backend operation latency by backend and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "method"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "storage_operation_errors_total",
+			Help:      "Number of failed storage backend operations by backend and method.",
+		}, []string{"backend", "method"}),
+		refreshPolls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "refresh_polls_total",
+			Help:      "Number of config refresh polls from clients by service.",
+		}, []string{"service"}),
+		watchSubscribers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: NAMESPACE,
+			Name:      "watch_subscribers",
+			Help:      "Number of active config watch subscribers.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.storageDuration,
+		m.storageErrors,
+		m.refreshPolls,
+		m.watchSubscribers,
+	)
+
+	return m
+}
+
+// Handler function
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest function
+func (m *Metrics) ObserveRequest(route string, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveStorage function
+func (m *Metrics) ObserveStorage(backend string, method string, duration time.Duration, err error) {
+	m.storageDuration.WithLabelValues(backend, method).Observe(duration.Seconds())
+
+	if err != nil {
+		m.storageErrors.WithLabelValues(backend, method).Inc()
+	}
+}
+
+// RefreshPoll function
+func (m *Metrics) RefreshPoll(service string) {
+	m.refreshPolls.WithLabelValues(service).Inc()
+}
+
+// WatchSubscribers function
+func (m *Metrics) WatchSubscribers(delta int) {
+	m.watchSubscribers.Add(float64(delta))
+}
+
+// RegisterLimiter function
+func (m *Metrics) RegisterLimiter(l *ratelimit.Limiter) {
+	for kind := range l.Rejected() {
+		kind := kind
+
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   NAMESPACE,
+			Name:        "limit_rejected_requests_total",
+			Help:        "Number of requests rejected by request limits.",
+			ConstLabels: prometheus.Labels{"limit": kind},
+		}, func() float64 {
+			return float64(l.Rejected()[kind])
+		}))
+	}
+}
+
+// RegisterStorage function
+func (m *Metrics) RegisterStorage(backend string, stats StatsFunc) {
+	m.registry.MustRegister(&storageCollector{
+		stats: stats,
+		services: prometheus.NewDesc(
+			prometheus.BuildFQName(NAMESPACE, "", "services"),
+			"Number of services with stored configs.",
+			nil, prometheus.Labels{"backend": backend},
+		),
+		versions: prometheus.NewDesc(
+			prometheus.BuildFQName(NAMESPACE, "", "service_versions"),
+			"Number of stored config versions by service.",
+			[]string{"service"}, prometheus.Labels{"backend": backend},
+		),
+	})
+}
+
+// storageCollector struct
+type storageCollector struct {
+	stats    StatsFunc
+	services *prometheus.Desc
+	versions *prometheus.Desc
+}
+
+// Describe function
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.services
+	ch <- c.versions
+}
+
+// Collect function
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.stats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.services, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.services, prometheus.GaugeValue, float64(len(stats)))
+
+	for service, count := range stats {
+		ch <- prometheus.MustNewConstMetric(c.versions, prometheus.GaugeValue, float64(count), service)
+	}
+}
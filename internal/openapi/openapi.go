@@ -0,0 +1,183 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+const VERSION = "3.0.3"
+
+// Document struct
+//
+// Документ спецификации OpenAPI 3. Описаны только те объекты
+// спецификации, которые используются сервером конфигураций.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Info struct
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem type
+//
+// Операции пути по HTTP методам в нижнем регистре.
+type PathItem map[string]*Operation
+
+// Operation struct
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Parameter struct
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody struct
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response struct
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header struct
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType struct
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema struct
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Components struct
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme struct
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityRequirement type
+type SecurityRequirement map[string][]string
+
+// Create function
+func Create(info Info) *Document {
+	return &Document{
+		OpenAPI: VERSION,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			Parameters:      map[string]*Parameter{},
+			Responses:       map[string]*Response{},
+			SecuritySchemes: map[string]*SecurityScheme{},
+		},
+	}
+}
+
+// AddOperation function
+//
+// Добавляет операцию для маршрута в формате httprouter
+// (параметры пути вида :name).
+func (d *Document) AddOperation(method string, route string, op *Operation) {
+	path := Path(route)
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = PathItem{}
+		d.Paths[path] = item
+	}
+
+	item[strings.ToLower(method)] = op
+}
+
+// Path function
+//
+// Преобразует маршрут httprouter в путь OpenAPI: /a/:b -> /a/{b}.
+func Path(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Ref function
+func Ref(kind string, name string) string {
+	return fmt.Sprintf("#/components/%s/%s", kind, name)
+}
+
+// SchemaRef function
+func SchemaRef(name string) *Schema {
+	return &Schema{Ref: Ref("schemas", name)}
+}
+
+// ParameterRef function
+func ParameterRef(name string) *Parameter {
+	return &Parameter{Ref: Ref("parameters", name)}
+}
+
+// ResponseRef function
+func ResponseRef(name string) *Response {
+	return &Response{Ref: Ref("responses", name)}
+}
+
+// JSONContent function
+func JSONContent(contentType string, schema *Schema) map[string]MediaType {
+	return map[string]MediaType{contentType: {Schema: schema}}
+}
@@ -0,0 +1,230 @@
+package ratelimit
+
+import (
+	"go-cloud-camp/internal/config"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Период очистки неиспользуемых ограничителей
+const CLEANUP_PERIOD = 5 * time.Minute
+
+// Limit kinds
+const (
+	LIMIT_BODY_SIZE = "body_size"
+	LIMIT_CLIENT    = "client"
+	LIMIT_SERVICE   = "service"
+	LIMIT_VERSIONS  = "versions"
+)
+
+// bucket struct
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+
+	// Возвращенные токены. Токен ограничителя нельзя вернуть после
+	// того, как он израсходован, поэтому они учитываются отдельно
+	// и расходуются раньше токенов ограничителя
+	refunded int
+}
+
+// bucketSet struct
+type bucketSet struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	buckets map[string]*bucket
+	cleanup time.Time
+}
+
+// Limiter struct
+type Limiter struct {
+	cfg      config.LimitsParams
+	clients  *bucketSet
+	services *bucketSet
+	versions *bucketSet
+	rejected map[string]*uint64
+}
+
+// Create function
+func Create(cfg *config.LimitsParams) *Limiter {
+	return &Limiter{
+		cfg:      *cfg,
+		clients:  newBucketSet(rate.Limit(cfg.ClientRate), cfg.ClientBurst),
+		services: newBucketSet(rate.Limit(cfg.ServiceRate), cfg.ServiceBurst),
+		versions: newBucketSet(rate.Limit(float64(cfg.VersionsPerMinute)/60), cfg.VersionsPerMinute),
+		rejected: map[string]*uint64{
+			LIMIT_BODY_SIZE: new(uint64),
+			LIMIT_CLIENT:    new(uint64),
+			LIMIT_SERVICE:   new(uint64),
+			LIMIT_VERSIONS:  new(uint64),
+		},
+	}
+}
+
+// MaxBodySize function
+func (l *Limiter) MaxBodySize() int64 {
+	return l.cfg.MaxBodySize
+}
+
+// MaxImportSize function
+func (l *Limiter) MaxImportSize() int64 {
+	return l.cfg.MaxImportSize
+}
+
+// AllowClient function
+func (l *Limiter) AllowClient(client string) (time.Duration, bool) {
+	return l.allow(LIMIT_CLIENT, l.clients, client)
+}
+
+// AllowService function
+func (l *Limiter) AllowService(service string) (time.Duration, bool) {
+	return l.allow(LIMIT_SERVICE, l.services, service)
+}
+
+// ReserveVersion function
+//
+// Ограничивает количество новых версий конфига сервиса в минуту.
+// Возвращает функцию отмены резерва, которую нужно вызвать, если
+// версия не была создана.
+func (l *Limiter) ReserveVersion(service string) (time.Duration, func(), bool) {
+	delay, cancel, ok := l.versions.reserve(service)
+	if !ok {
+		atomic.AddUint64(l.rejected[LIMIT_VERSIONS], 1)
+	}
+
+	return delay, cancel, ok
+}
+
+// RejectBodySize function
+func (l *Limiter) RejectBodySize() {
+	atomic.AddUint64(l.rejected[LIMIT_BODY_SIZE], 1)
+}
+
+// Rejected function
+//
+// Возвращает количество отклоненных запросов по видам ограничений.
+func (l *Limiter) Rejected() map[string]uint64 {
+	result := make(map[string]uint64, len(l.rejected))
+	for kind, counter := range l.rejected {
+		result[kind] = atomic.LoadUint64(counter)
+	}
+
+	return result
+}
+
+// RetryAfter function
+//
+// Значение заголовка Retry-After в целых секундах.
+func RetryAfter(delay time.Duration) int {
+	return int(math.Max(1, math.Ceil(delay.Seconds())))
+}
+
+// allow function
+func (l *Limiter) allow(kind string, set *bucketSet, key string) (time.Duration, bool) {
+	delay, ok := set.allow(key)
+	if !ok {
+		atomic.AddUint64(l.rejected[kind], 1)
+	}
+
+	return delay, ok
+}
+
+// newBucketSet function
+func newBucketSet(limit rate.Limit, burst int) *bucketSet {
+	return &bucketSet{
+		limit:   limit,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		cleanup: time.Now(),
+	}
+}
+
+// allow function
+func (bs *bucketSet) allow(key string) (time.Duration, bool) {
+	delay, _, ok := bs.reserve(key)
+	return delay, ok
+}
+
+// reserve function
+func (bs *bucketSet) reserve(key string) (time.Duration, func(), bool) {
+	// Нулевое значение отключает ограничение
+	if bs.limit <= 0 || bs.burst <= 0 {
+		return 0, func() {}, true
+	}
+
+	now := time.Now()
+
+	bs.mu.Lock()
+	b, ok := bs.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(bs.limit, bs.burst)}
+		bs.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	if now.Sub(bs.cleanup) > CLEANUP_PERIOD {
+		bs.removeStale(now)
+	}
+
+	if bs.takeRefunded(b, now) {
+		bs.mu.Unlock()
+		return 0, bs.refund(b), true
+	}
+	bs.mu.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, func() {}, false
+	}
+
+	return 0, bs.refund(b), true
+}
+
+// takeRefunded function
+//
+// Расходует возвращенный токен. Вместе с накопленными токенами
+// ограничителя возвращенных токенов не может быть больше burst.
+func (bs *bucketSet) takeRefunded(b *bucket, now time.Time) bool {
+	if free := bs.burst - int(b.limiter.TokensAt(now)); b.refunded > free {
+		b.refunded = free
+	}
+
+	if b.refunded <= 0 {
+		return false
+	}
+
+	b.refunded--
+	return true
+}
+
+// refund function
+//
+// Функция однократного возврата токена.
+func (bs *bucketSet) refund(b *bucket) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			bs.mu.Lock()
+			b.refunded++
+			bs.mu.Unlock()
+		})
+	}
+}
+
+// removeStale function
+func (bs *bucketSet) removeStale(now time.Time) {
+	for key, b := range bs.buckets {
+		if now.Sub(b.lastSeen) > CLEANUP_PERIOD {
+			delete(bs.buckets, key)
+		}
+	}
+
+	bs.cleanup = now
+}
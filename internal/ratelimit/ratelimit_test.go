@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"go-cloud-camp/internal/config"
+	"testing"
+)
+
+func TestReserveVersionRefund(t *testing.T) {
+	l := Create(&config.LimitsParams{VersionsPerMinute: 1})
+
+	_, refund, ok := l.ReserveVersion("svc")
+	if !ok {
+		t.Fatal("first version is rejected")
+	}
+
+	// Несозданная версия не расходует ограничение, повторный
+	// возврат не добавляет токенов
+	refund()
+	refund()
+
+	if _, _, ok = l.ReserveVersion("svc"); !ok {
+		t.Fatal("version is rejected after refund")
+	}
+
+	delay, _, ok := l.ReserveVersion("svc")
+	if ok || delay <= 0 {
+		t.Fatalf("second version in a minute is allowed: delay=%s", delay)
+	}
+
+	if _, _, ok = l.ReserveVersion("other"); !ok {
+		t.Fatal("version of another service is rejected")
+	}
+
+	if rejected := l.Rejected()[LIMIT_VERSIONS]; rejected != 1 {
+		t.Fatalf("rejected versions = %d, want 1", rejected)
+	}
+}
+
+func TestDisabledLimits(t *testing.T) {
+	l := Create(&config.LimitsParams{})
+
+	for i := 0; i < 100; i++ {
+		if _, ok := l.AllowClient("c"); !ok {
+			t.Fatal("client is limited with zero rate")
+		}
+		if _, refund, ok := l.ReserveVersion("svc"); !ok {
+			t.Fatal("version is limited with zero rate")
+		} else {
+			refund()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package redact
+
+import (
+	"encoding/json"
+	"go-cloud-camp/internal/config"
+	"go-cloud-camp/internal/jsondoc"
+	"go-cloud-camp/internal/secrets"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Policy struct
+type Policy struct {
+	patterns []string
+}
+
+// Create function
+func Create(cfg *config.RedactionParams) *Policy {
+	p := &Policy{
+		patterns: make([]string, 0, len(cfg.Keys)),
+	}
+
+	for _, pattern := range cfg.Keys {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			p.patterns = append(p.patterns, strings.ToLower(pattern))
+		}
+	}
+
+	return p
+}
+
+// MatchKey function
+//
+// Имена ключей сравниваются с шаблонами без учета регистра,
+// в шаблонах допускается синтаксис path.Match (*, ?, [...]).
+func (p *Policy) MatchKey(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, pattern := range p.patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSON function
+//
+// Заменяет значения чувствительных ключей и секретные значения
+// на секретную заглушку в произвольном JSON документе.
+func (p *Policy) JSON(data json.RawMessage) (json.RawMessage, error) {
+	result, _, err := jsondoc.Transform(data, func(key string, value interface{}) (interface{}, bool, error) {
+		if _, _, ok := secrets.Marker(value); ok || (key != "" && p.MatchKey(key)) {
+			return secrets.REDACTED_VALUE, true, nil
+		}
+
+		return value, false, nil
+	})
+
+	return result, err
+}
+
+// Payload function
+//
+// Вариант JSON для записи в лог, невалидный JSON полностью скрывается.
+func (p *Policy) Payload(data json.RawMessage) string {
+	result, err := p.JSON(data)
+	if err != nil {
+		return secrets.REDACTED_VALUE
+	}
+
+	return string(result)
+}
+
+// URI function
+func (p *Policy) URI(uri string) string {
+	u, err := url.ParseRequestURI(uri)
+	if err != nil || u.RawQuery == "" {
+		return uri
+	}
+
+	query := u.Query()
+	changed := false
+
+	for key := range query {
+		if p.MatchKey(key) {
+			query[key] = []string{secrets.REDACTED_VALUE}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return uri
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
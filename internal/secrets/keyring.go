@@ -0,0 +1,156 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Size of master and data encryption keys (AES-256)
+const KEY_SIZE = 32
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+var ErrNoPrimaryKey = errors.New("primary encryption key is not defined")
+
+// keyFile struct
+//
+// Формат файла ключей:
+//
+//	primary: key-2
+//	keys:
+//	  key-1: <base64, 32 байта>
+//	  key-2: <base64, 32 байта>
+type keyFile struct {
+	Primary string            `yaml:"primary"`
+	Keys    map[string]string `yaml:"keys"`
+}
+
+// Keyring struct
+type Keyring struct {
+	path    string
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadKeyring function
+func LoadKeyring(path string) (*Keyring, error) {
+	kr := &Keyring{path: path}
+
+	if err := kr.Reload(); err != nil {
+		return nil, err
+	}
+
+	return kr, nil
+}
+
+// Reload function
+func (kr *Keyring) Reload() error {
+	raw, err := os.ReadFile(kr.path)
+	if err != nil {
+		return err
+	}
+
+	kf := &keyFile{}
+	if err = yaml.Unmarshal(raw, kf); err != nil {
+		return err
+	}
+
+	if _, ok := kf.Keys[kf.Primary]; !ok {
+		return ErrNoPrimaryKey
+	}
+
+	keys := make(map[string]cipher.AEAD, len(kf.Keys))
+	for kid, encoded := range kf.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		if keys[kid], err = newAEAD(key); err != nil {
+			return fmt.Errorf("key %q: %w", kid, err)
+		}
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	kr.primary = kf.Primary
+	kr.keys = keys
+
+	return nil
+}
+
+// Primary function
+func (kr *Keyring) Primary() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	return kr.primary
+}
+
+// wrapKey function
+func (kr *Keyring) wrapKey(dek []byte) (string, []byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	wrapped, err := seal(kr.keys[kr.primary], dek)
+
+	return kr.primary, wrapped, err
+}
+
+// unwrapKey function
+func (kr *Keyring) unwrapKey(kid string, wrapped []byte) ([]byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	kek, ok := kr.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	return open(kek, wrapped)
+}
+
+// newAEAD function
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KEY_SIZE {
+		return nil, fmt.Errorf("invalid key size %d, expected %d", len(key), KEY_SIZE)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal function
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open function
+func open(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, data := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, data, nil)
+}
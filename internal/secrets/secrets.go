@@ -0,0 +1,303 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"go-cloud-camp/internal/jsondoc"
+	"io"
+)
+
+// Маркеры секретных значений в JSON конфигурации.
+//
+// Клиент передает секрет в виде {"$secret": value}, на сервере значение
+// шифруется и хранится в виде {"$encrypted": {"kid": ..., "key": ..., "data": ...}}
+const (
+	SECRET_MARKER    = "$secret"
+	ENCRYPTED_MARKER = "$encrypted"
+	REDACTED_VALUE   = "[REDACTED]"
+)
+
+// encryptedValue struct
+type encryptedValue struct {
+	Kid  string `json:"kid"`
+	Key  []byte `json:"key"`
+	Data []byte `json:"data"`
+}
+
+// markerFunc type
+//
+// Вызывается для каждого объекта-маркера, возвращает новое значение
+// и признак того, что значение было заменено.
+type markerFunc func(marker string, value interface{}) (interface{}, bool, error)
+
+// HasSecrets function
+//
+// Проверяет, есть ли в документе объекты-маркеры. Ключ маркера может
+// быть записан с экранированием, например "\u0024secret", поэтому
+// проверяется разобранный документ.
+func HasSecrets(data json.RawMessage) bool {
+	return len(markers(data)) > 0
+}
+
+// HasMarker function
+//
+// Проверяет, есть ли в документе объекты-маркеры marker.
+func HasMarker(data json.RawMessage, marker string) bool {
+	return markers(data)[marker]
+}
+
+// CheckEncrypted function
+//
+// Проверяет формат зашифрованных значений документа, полученного
+// не от клиента, а, например, из архива конфигураций. Возможность
+// расшифровки значений не проверяется.
+func CheckEncrypted(data json.RawMessage) error {
+	_, _, err := transform(data, func(marker string, value interface{}) (interface{}, bool, error) {
+		if marker != ENCRYPTED_MARKER {
+			return value, false, nil
+		}
+
+		ev, err := decodeEncryptedValue(value)
+		if err != nil {
+			return value, false, err
+		}
+
+		if ev.Kid == "" || len(ev.Key) == 0 || len(ev.Data) == 0 {
+			return value, false, errors.New("malformed encrypted value")
+		}
+
+		return value, false, nil
+	})
+
+	return err
+}
+
+// Seal function
+func (kr *Keyring) Seal(data json.RawMessage) (json.RawMessage, error) {
+	result, _, err := transform(data, func(marker string, value interface{}) (interface{}, bool, error) {
+		if marker != SECRET_MARKER {
+			return value, false, nil
+		}
+
+		sealed, err := kr.encrypt(value)
+		return sealed, err == nil, err
+	})
+
+	return result, err
+}
+
+// Open function
+func (kr *Keyring) Open(data json.RawMessage) (json.RawMessage, error) {
+	result, _, err := transform(data, func(marker string, value interface{}) (interface{}, bool, error) {
+		if marker != ENCRYPTED_MARKER {
+			return value, false, nil
+		}
+
+		plain, err := kr.decrypt(value)
+		return plain, err == nil, err
+	})
+
+	return result, err
+}
+
+// OpenMarked function
+//
+// Расшифровывает значения, как Open, но сохраняет их в маркерах
+// {"$secret": value}, поэтому результат можно сохранить как новую
+// версию конфига без потери секретов.
+func (kr *Keyring) OpenMarked(data json.RawMessage) (json.RawMessage, error) {
+	result, _, err := transform(data, func(marker string, value interface{}) (interface{}, bool, error) {
+		if marker != ENCRYPTED_MARKER {
+			return value, false, nil
+		}
+
+		plain, err := kr.decrypt(value)
+		if err != nil {
+			return value, false, err
+		}
+
+		return map[string]interface{}{SECRET_MARKER: plain}, true, nil
+	})
+
+	return result, err
+}
+
+// Reencrypt function
+//
+// Перешифровывает значения, зашифрованные не основным ключом.
+// Возвращает признак того, что документ был изменен.
+func (kr *Keyring) Reencrypt(data json.RawMessage) (json.RawMessage, bool, error) {
+	primary := kr.Primary()
+
+	return transform(data, func(marker string, value interface{}) (interface{}, bool, error) {
+		if marker != ENCRYPTED_MARKER {
+			return value, false, nil
+		}
+
+		ev, err := decodeEncryptedValue(value)
+		if err != nil || ev.Kid == primary {
+			return value, false, err
+		}
+
+		plain, err := kr.decrypt(value)
+		if err != nil {
+			return value, false, err
+		}
+
+		sealed, err := kr.encrypt(plain)
+		return sealed, err == nil, err
+	})
+}
+
+// Redact function
+func Redact(data json.RawMessage) (json.RawMessage, error) {
+	result, _, err := transform(data, func(marker string, value interface{}) (interface{}, bool, error) {
+		return REDACTED_VALUE, true, nil
+	})
+
+	return result, err
+}
+
+// encrypt function
+func (kr *Keyring) encrypt(value interface{}) (interface{}, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Для каждого значения генерируется свой ключ шифрования данных (DEK),
+	// который затем шифруется основным ключом из файла ключей (KEK)
+	dek := make([]byte, KEY_SIZE)
+	if _, err = io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &encryptedValue{}
+
+	if ev.Data, err = seal(aead, plaintext); err != nil {
+		return nil, err
+	}
+
+	if ev.Kid, ev.Key, err = kr.wrapKey(dek); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{ENCRYPTED_MARKER: ev}, nil
+}
+
+// decrypt function
+func (kr *Keyring) decrypt(value interface{}) (interface{}, error) {
+	ev, err := decodeEncryptedValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dek, err := kr.unwrapKey(ev.Kid, ev.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(aead, ev.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsondoc.Decode(plaintext)
+}
+
+// decodeEncryptedValue function
+func decodeEncryptedValue(value interface{}) (*encryptedValue, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &encryptedValue{}
+	if err = json.Unmarshal(raw, ev); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// Marker function
+//
+// Проверяет, является ли значение объектом-маркером секрета.
+func Marker(value interface{}) (string, interface{}, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return "", nil, false
+	}
+
+	for key, inner := range obj {
+		if key == SECRET_MARKER || key == ENCRYPTED_MARKER {
+			return key, inner, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// markers function
+//
+// Маркеры, которые есть в документе. Вложенные в маркеры значения
+// не проверяются.
+func markers(data json.RawMessage) map[string]bool {
+	// Без символа $ маркер возможен только с экранированием
+	if bytes.IndexByte(data, '$') < 0 && !bytes.Contains(data, []byte(`\u`)) {
+		return nil
+	}
+
+	doc, err := jsondoc.Decode(data)
+	if err != nil {
+		return nil
+	}
+
+	found := map[string]bool{}
+	changed := false
+
+	_, _ = jsondoc.Walk(doc, func(_ string, value interface{}) (interface{}, bool, error) {
+		marker, _, ok := Marker(value)
+		if ok {
+			found[marker] = true
+		}
+
+		return value, ok, nil
+	}, &changed)
+
+	return found
+}
+
+// transform function
+func transform(data json.RawMessage, fn markerFunc) (json.RawMessage, bool, error) {
+	// Документы без маркеров возвращаем как есть, без повторной сериализации
+	if !HasSecrets(data) {
+		return data, false, nil
+	}
+
+	return jsondoc.Transform(data, func(_ string, value interface{}) (interface{}, bool, error) {
+		marker, inner, ok := Marker(value)
+		if !ok {
+			return value, false, nil
+		}
+
+		result, replaced, err := fn(marker, inner)
+		if err != nil || !replaced {
+			return value, false, err
+		}
+
+		return result, true, nil
+	})
+}
@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testKey1 = "j0l1n9w2aT2+HlANcbPV1SaGyHjzhcwxeMuan93Y8Pg="
+	testKey2 = "0r2Zz0b9C5o2uJ4Cw8bDqk9sY0bN6oQ7yM0l3nJ2p4E="
+)
+
+// writeKeyFile function
+func writeKeyFile(t *testing.T, path string, primary string) {
+	t.Helper()
+
+	content := "primary: " + primary + "\nkeys:\n  k1: " + testKey1 + "\n  k2: " + testKey2 + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKeyring function
+func testKeyring(t *testing.T) (*Keyring, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.yml")
+	writeKeyFile(t, path, "k1")
+
+	kr, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kr, path
+}
+
+func TestSealOpen(t *testing.T) {
+	kr, _ := testKeyring(t)
+
+	sealed, err := kr.Seal(json.RawMessage(`{"db":{"password":{"$secret":"p"}},"port":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(sealed), `"p"`) || !HasMarker(sealed, ENCRYPTED_MARKER) || HasMarker(sealed, SECRET_MARKER) {
+		t.Fatalf("secret is not sealed: %s", sealed)
+	}
+
+	opened, err := kr.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(opened) != `{"db":{"password":"p"},"port":1}` {
+		t.Fatalf("unexpected opened config: %s", opened)
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	kr, path := testKeyring(t)
+
+	sealed, err := kr.Seal(json.RawMessage(`{"token":{"$secret":"t"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, changed, err := kr.Reencrypt(sealed); err != nil || changed {
+		t.Fatalf("value sealed with the primary key is reencrypted: changed=%v, err=%v", changed, err)
+	}
+
+	writeKeyFile(t, path, "k2")
+	if err := kr.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, changed, err := kr.Reencrypt(sealed)
+	if err != nil || !changed {
+		t.Fatalf("value is not reencrypted: changed=%v, err=%v", changed, err)
+	}
+
+	if !strings.Contains(string(rotated), `"kid":"k2"`) {
+		t.Fatalf("value is not sealed with the new primary key: %s", rotated)
+	}
+
+	opened, err := kr.Open(rotated)
+	if err != nil || string(opened) != `{"token":"t"}` {
+		t.Fatalf("unexpected opened config: %s, err=%v", opened, err)
+	}
+}
+
+func TestCheckEncrypted(t *testing.T) {
+	kr, _ := testKeyring(t)
+
+	sealed, err := kr.Seal(json.RawMessage(`{"a":{"$secret":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		data  string
+		valid bool
+	}{
+		{"sealed", string(sealed), true},
+		{"plain", `{"a":1}`, true},
+		{"empty", `{"a":{"$encrypted":{}}}`, false},
+		{"string", `{"a":{"$encrypted":"x"}}`, false},
+		{"no key", `{"a":{"$encrypted":{"kid":"k1","data":"AAAA"}}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckEncrypted(json.RawMessage(tt.data))
+			if (err == nil) != tt.valid {
+				t.Fatalf("CheckEncrypted() = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestHasSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"plain", `{"a":1,"b":"$secret"}`, false},
+		{"secret", `{"a":{"$secret":1}}`, true},
+		{"encrypted", `[{"$encrypted":{}}]`, true},
+		{"escaped", `{"a":{"\u0024secret":"p"}}`, true},
+		{"not a marker", `{"a":{"$secret":1,"b":2}}`, false},
+		{"invalid", `{"$secret":`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasSecrets(json.RawMessage(tt.data)); got != tt.want {
+				t.Fatalf("HasSecrets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealEscapedMarker(t *testing.T) {
+	kr, _ := testKeyring(t)
+
+	sealed, err := kr.Seal(json.RawMessage(`{"a":{"\u0024secret":"p"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(sealed), `"p"`) || !HasMarker(sealed, ENCRYPTED_MARKER) {
+		t.Fatalf("escaped secret is not sealed: %s", sealed)
+	}
+}
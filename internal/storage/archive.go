@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/secrets"
+	"sort"
+)
+
+// Export function
+//
+// Передает функции fn все версии конфигов всех сервисов в порядке
+// имен сервисов и номеров версий. Данные передаются в том виде,
+// в котором они хранятся, секретные значения остаются зашифрованными.
+func (s *AppStorage) Export(ctx context.Context, fn func(*common.ConfigRecord) error) error {
+	services, err := s.backend.ListServices(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		records, err := s.backend.ExportConfigs(ctx, service)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Import function
+//
+// Загружает версии конфигов из архива. При Replace конфиг каждого
+// сервиса из архива заменяется версиями из архива, а конфиги сервисов,
+// которых нет в архиве, удаляются после загрузки всех сервисов, иначе
+// версии добавляются к существующим конфигам сервисов. При
+// PreserveVersions версии сохраняют номера из архива, версии с уже
+// занятыми номерами пропускаются.
+//
+// Загрузка не атомарна: при ошибке сервисы, загруженные до нее,
+// остаются замененными. Конфиг сервиса, при загрузке которого произошла
+// ошибка, восстанавливается из версий, сохраненных перед удалением.
+func (s *AppStorage) Import(ctx context.Context, records []*common.ConfigRecord, params *common.ImportParams) (*common.ImportResult, error) {
+	byService := map[string][]*common.ConfigRecord{}
+
+	// Данные проверяются до изменения хранилища, чтобы ошибка
+	// в архиве не оставила хранилище очищенным
+	for _, record := range records {
+		data, err := s.sealImported(record)
+		if err != nil {
+			return nil, err
+		}
+
+		imported := *record
+		imported.Data = data
+		byService[record.Service] = append(byService[record.Service], &imported)
+	}
+
+	result := &common.ImportResult{Services: []string{}, Deleted: []string{}}
+
+	for service := range byService {
+		result.Services = append(result.Services, service)
+	}
+	sort.Strings(result.Services)
+
+	var existing []string
+	if params.Replace {
+		var err error
+		if existing, err = s.backend.ListServices(ctx); err != nil {
+			return nil, err
+		}
+		sort.Strings(existing)
+
+		if len(records) == 0 && len(existing) > 0 && !params.AllowEmpty {
+			return nil, fmt.Errorf("%w: archive has no configs, replace would delete all services", common.ErrInvalidArchive)
+		}
+	}
+
+	for _, service := range result.Services {
+		serviceRecords := byService[service]
+		sort.Slice(serviceRecords, func(i, j int) bool {
+			return serviceRecords[i].Version < serviceRecords[j].Version
+		})
+
+		// Конфиг сервиса удаляется непосредственно перед загрузкой,
+		// поэтому ошибка загрузки не затрагивает остальные сервисы
+		var backup []*common.ConfigRecord
+		if params.Replace && contains(existing, service) {
+			var err error
+			if backup, err = s.backend.ExportConfigs(ctx, service); err != nil {
+				return nil, err
+			}
+
+			if err := s.backend.DropService(ctx, service); err != nil {
+				return nil, err
+			}
+		}
+
+		latest, err := s.latestVersion(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+
+		imported, err := s.backend.ImportConfigs(ctx, service, serviceRecords, params.PreserveVersions)
+		if err != nil {
+			if backup != nil {
+				err = s.restore(service, backup, err)
+			}
+			return nil, err
+		}
+		result.Imported += len(imported)
+		result.Skipped += len(serviceRecords) - len(imported)
+
+		// Подписчики получают новую версию, только если загружена
+		// версия новее последней версии сервиса
+		if n := len(imported); n > 0 && imported[n-1].Version > latest {
+			s.publish(imported[n-1])
+		}
+	}
+
+	for _, service := range existing {
+		if _, ok := byService[service]; ok {
+			continue
+		}
+
+		if err := s.backend.DropService(ctx, service); err != nil {
+			return nil, err
+		}
+		result.Deleted = append(result.Deleted, service)
+	}
+
+	return result, nil
+}
+
+// restore function
+//
+// Восстанавливает версии конфига сервиса, удаленного перед загрузкой,
+// после ошибки загрузки importErr. Восстановление выполняется и после
+// отмены запроса, поэтому контекст запроса не используется.
+func (s *AppStorage) restore(service string, backup []*common.ConfigRecord, importErr error) error {
+	ctx := context.Background()
+
+	if err := s.backend.DropService(ctx, service); err != nil {
+		return fmt.Errorf("import of service %q failed: %w; couldn't restore its versions: %v", service, importErr, err)
+	}
+
+	if _, err := s.backend.ImportConfigs(ctx, service, backup, true); err != nil {
+		return fmt.Errorf("import of service %q failed: %w; couldn't restore its versions: %v", service, importErr, err)
+	}
+
+	return fmt.Errorf("import of service %q failed, its versions are restored: %w", service, importErr)
+}
+
+// latestVersion function
+func (s *AppStorage) latestVersion(ctx context.Context, service string) (int, error) {
+	versions, err := s.backend.ListVersions(ctx, service)
+	if errors.Is(err, common.ErrServiceNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return versions[len(versions)-1].Version, nil
+}
+
+// sealImported function
+//
+// Шифрует открытые секретные значения {"$secret": value} версии
+// из архива. Зашифрованные значения сохраняются без изменений, для
+// их чтения сервер должен использовать тот же файл ключей.
+func (s *AppStorage) sealImported(record *common.ConfigRecord) (json.RawMessage, error) {
+	if err := secrets.CheckEncrypted(record.Data); err != nil {
+		return nil, fmt.Errorf("%w: version %d of service %q: %v", common.ErrInvalidArchive, record.Version, record.Service, err)
+	}
+
+	if !secrets.HasMarker(record.Data, secrets.SECRET_MARKER) {
+		return record.Data, nil
+	}
+
+	if s.keyring == nil {
+		return nil, common.ErrSecretsDisabled
+	}
+
+	return s.keyring.Seal(record.Data)
+}
+
+// contains function
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
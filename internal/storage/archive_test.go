@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go-cloud-camp/internal/archive"
+	"go-cloud-camp/internal/common"
+	"reflect"
+	"testing"
+)
+
+// archiveRecords function
+func archiveRecords(service string, data ...string) []*common.ConfigRecord {
+	records := make([]*common.ConfigRecord, 0, len(data))
+	for i, d := range data {
+		records = append(records, &common.ConfigRecord{
+			VersionInfo: common.VersionInfo{Service: service, Version: i + 1},
+			Data:        json.RawMessage(d),
+		})
+	}
+
+	return records
+}
+
+func TestImportReplace(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	backend.add("a", 1, json.RawMessage(`{"old":1}`))
+	backend.add("a", 2, json.RawMessage(`{"old":2}`))
+	backend.add("c", 1, json.RawMessage(`{"c":1}`))
+
+	records := append(archiveRecords("a", `{"new":1}`), archiveRecords("b", `{"b":1}`)...)
+
+	result, err := s.Import(ctx, records, &common.ImportParams{Replace: true, PreserveVersions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(result.Services, []string{"a", "b"}) || !reflect.DeepEqual(result.Deleted, []string{"c"}) || result.Imported != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	versions, err := backend.ListVersions(ctx, "a")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("versions of a = %v, %v, want only the archived version", versions, err)
+	}
+
+	if _, err := backend.ListVersions(ctx, "c"); !errors.Is(err, common.ErrServiceNotFound) {
+		t.Fatalf("service missing from the archive is kept: %v", err)
+	}
+}
+
+func TestImportReplaceFailureKeepsOtherServices(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	backend.add("a", 1, json.RawMessage(`{"a":1}`))
+	backend.add("b", 1, json.RawMessage(`{"b":1}`))
+	backend.add("c", 1, json.RawMessage(`{"c":1}`))
+
+	backend.failService = "a"
+	backend.failImport = errors.New("import failed")
+
+	records := append(archiveRecords("a", `{"a":2}`, `{"a":3}`), archiveRecords("b", `{"b":2}`)...)
+
+	if _, err := s.Import(ctx, records, &common.ImportParams{Replace: true, PreserveVersions: true}); !errors.Is(err, backend.failImport) {
+		t.Fatalf("Import() error = %v, want %v", err, backend.failImport)
+	}
+
+	// Конфиг сервиса с ошибкой загрузки восстанавливается, остальные
+	// сервисы не затрагиваются
+	for _, service := range []string{"a", "b", "c"} {
+		record, err := backend.ReadConfig(ctx, service, 1)
+		if err != nil {
+			t.Fatalf("service %s is lost after failed import: %v", service, err)
+		}
+		if want := `{"` + service + `":1}`; string(record.Data) != want {
+			t.Fatalf("service %s data = %s, want %s", service, record.Data, want)
+		}
+	}
+}
+
+func TestImportReplaceRejectsEmptyArchive(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	backend.add("a", 1, json.RawMessage(`{"a":1}`))
+
+	if _, err := s.Import(ctx, nil, &common.ImportParams{Replace: true}); !errors.Is(err, common.ErrInvalidArchive) {
+		t.Fatalf("Import() error = %v, want %v", err, common.ErrInvalidArchive)
+	}
+
+	if _, err := backend.ListVersions(ctx, "a"); err != nil {
+		t.Fatalf("service is deleted by rejected import: %v", err)
+	}
+
+	result, err := s.Import(ctx, nil, &common.ImportParams{Replace: true, AllowEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(result.Deleted, []string{"a"}) {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	keyring := testKeyring(t)
+	src, _ := newTestStorage(t, keyring)
+	ctx := context.Background()
+
+	if _, err := src.Create(ctx, &common.RequestData{Service: "svc", Data: json.RawMessage(`{"host":"a","password":{"$secret":"p1"}}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Update(ctx, &common.RequestData{Service: "svc", Data: json.RawMessage(`{"host":"b","password":{"$secret":"p2"}}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Create(ctx, &common.RequestData{Service: "other", Data: json.RawMessage(`{"n":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	aw := archive.NewWriter(buf)
+	if err := aw.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Export(ctx, aw.Write); err != nil {
+		t.Fatal(err)
+	}
+	if err := aw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Секретные значения в архиве остаются зашифрованными
+	if bytes.Contains(buf.Bytes(), []byte("p1")) {
+		t.Fatalf("archive contains a plaintext secret: %s", buf.Bytes())
+	}
+
+	records, err := archive.ReadAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, backend := newTestStorage(t, keyring)
+
+	result, err := dst.Import(ctx, records, &common.ImportParams{PreserveVersions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 3 || result.Skipped != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	record, err := dst.Read(ctx, "svc", 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(record.Data) != `{"host":"b","password":"p2"}` {
+		t.Fatalf("imported data = %s", record.Data)
+	}
+
+	// Повторная загрузка с сохранением номеров пропускает все версии
+	result, err = dst.Import(ctx, records, &common.ImportParams{PreserveVersions: true})
+	if err != nil || result.Imported != 0 || result.Skipped != 3 {
+		t.Fatalf("second import = %+v, %v", result, err)
+	}
+
+	if counts, _ := backend.CountVersions(ctx); counts["svc"] != 2 || counts["other"] != 1 {
+		t.Fatalf("versions after import = %v", counts)
+	}
+}
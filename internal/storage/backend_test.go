@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/config"
+	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/redact"
+	"go-cloud-camp/internal/secrets"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// memService struct
+type memService struct {
+	next     int
+	versions map[int]*common.ConfigRecord
+}
+
+// memBackend struct
+//
+// Хранилище конфигов в памяти для тестов AppStorage. Ошибка failImport
+// возвращается один раз при загрузке версий сервиса failService после
+// записи первой версии. Число чтений,
+// отмечающих версии как прочитанные, считается по сервисам в reads.
+type memBackend struct {
+	mu       sync.Mutex
+	services map[string]*memService
+	reads    map[string]int
+
+	failService string
+	failImport  error
+}
+
+// newTestStorage function
+func newTestStorage(t *testing.T, keyring *secrets.Keyring) (*AppStorage, *memBackend) {
+	t.Helper()
+
+	backend := &memBackend{services: map[string]*memService{}, reads: map[string]int{}}
+
+	return &AppStorage{
+		logger:   &logging.Logger{SugaredLogger: zap.NewNop().Sugar()},
+		backend:  backend,
+		keyring:  keyring,
+		redactor: redact.Create(&config.RedactionParams{Keys: []string{"password"}}),
+		done:     make(chan struct{}),
+	}, backend
+}
+
+// testKeyring function
+//
+// Файл ключей k1 и k2 с основным ключом primary.
+func testKeyring(t *testing.T) *secrets.Keyring {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.yml")
+	writeKeyFile(t, path, "k1")
+
+	kr, err := secrets.LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kr
+}
+
+// writeKeyFile function
+func writeKeyFile(t *testing.T, path string, primary string) {
+	t.Helper()
+
+	content := "primary: " + primary + "\nkeys:\n" +
+		"  k1: j0l1n9w2aT2+HlANcbPV1SaGyHjzhcwxeMuan93Y8Pg=\n" +
+		"  k2: 0r2Zz0b9C5o2uJ4Cw8bDqk9sY0bN6oQ7yM0l3nJ2p4E=\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// add function
+func (m *memBackend) add(service string, version int, data json.RawMessage) *common.VersionInfo {
+	s := m.services[service]
+	if s == nil {
+		s = &memService{next: 1, versions: map[int]*common.ConfigRecord{}}
+		m.services[service] = s
+	}
+
+	info := common.VersionInfo{
+		Service:   service,
+		Version:   version,
+		CreatedAt: time.Now().UTC(),
+		ETag:      common.ETag(service, version, data),
+	}
+	s.versions[version] = &common.ConfigRecord{VersionInfo: info, Data: data}
+
+	if version >= s.next {
+		s.next = version + 1
+	}
+
+	return &info
+}
+
+// sorted function
+func (s *memService) sorted() []*common.ConfigRecord {
+	records := make([]*common.ConfigRecord, 0, len(s.versions))
+	for _, record := range s.versions {
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+
+	return records
+}
+
+// CreateConfig function
+func (m *memBackend) CreateConfig(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.services[data.Service]; ok {
+		return nil, common.ErrAlreadyCreated
+	}
+
+	return m.add(data.Service, 1, data.Data), nil
+}
+
+// ReadConfig function
+func (m *memBackend) ReadConfig(ctx context.Context, service string, version int) (*common.ConfigRecord, error) {
+	m.mu.Lock()
+	m.reads[service]++
+	m.mu.Unlock()
+
+	return m.PeekConfig(ctx, service, version)
+}
+
+// PeekConfig function
+func (m *memBackend) PeekConfig(ctx context.Context, service string, version int) (*common.ConfigRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.services[service]
+	if !ok || len(s.versions) == 0 {
+		return nil, common.ErrNotFound
+	}
+
+	if version == 0 {
+		records := s.sorted()
+		version = records[len(records)-1].Version
+	}
+
+	record, ok := s.versions[version]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+
+	result := *record
+	return &result, nil
+}
+
+// UpdateConfig function
+func (m *memBackend) UpdateConfig(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.services[data.Service]
+	if !ok {
+		return nil, common.ErrServiceNotFound
+	}
+
+	if data.IfMatch != common.EMPTY_STRING {
+		records := s.sorted()
+		if len(records) == 0 || records[len(records)-1].ETag != data.IfMatch {
+			return nil, common.ErrPreconditionFailed
+		}
+	}
+
+	return m.add(data.Service, s.next, data.Data), nil
+}
+
+// DeleteConfig function
+func (m *memBackend) DeleteConfig(ctx context.Context, service string, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if version == 0 {
+		delete(m.services, service)
+		return nil
+	}
+
+	s, ok := m.services[service]
+	if !ok {
+		return common.ErrNotFound
+	}
+	if _, ok := s.versions[version]; !ok {
+		return common.ErrNotFound
+	}
+	delete(s.versions, version)
+
+	return nil
+}
+
+// ListServices function
+func (m *memBackend) ListServices(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var services []string
+	for service := range m.services {
+		services = append(services, service)
+	}
+
+	return services, nil
+}
+
+// ListVersions function
+func (m *memBackend) ListVersions(ctx context.Context, service string) ([]*common.VersionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.services[service]
+	if !ok || len(s.versions) == 0 {
+		return nil, common.ErrServiceNotFound
+	}
+
+	var versions []*common.VersionInfo
+	for _, record := range s.sorted() {
+		info := record.VersionInfo
+		versions = append(versions, &info)
+	}
+
+	return versions, nil
+}
+
+// CountVersions function
+func (m *memBackend) CountVersions(ctx context.Context) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := map[string]int{}
+	for service, s := range m.services {
+		counts[service] = len(s.versions)
+	}
+
+	return counts, nil
+}
+
+// RewriteConfigs function
+func (m *memBackend) RewriteConfigs(ctx context.Context, service string, fn common.RewriteFunc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.services[service]
+	if !ok {
+		return nil
+	}
+
+	for _, record := range s.sorted() {
+		data, changed, err := fn(record.Data)
+		if err != nil {
+			return err
+		}
+		if changed {
+			record.Data = data
+		}
+	}
+
+	return nil
+}
+
+// ExportConfigs function
+func (m *memBackend) ExportConfigs(ctx context.Context, service string) ([]*common.ConfigRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.services[service]
+	if !ok {
+		return nil, nil
+	}
+
+	return s.sorted(), nil
+}
+
+// ImportConfigs function
+func (m *memBackend) ImportConfigs(ctx context.Context, service string, records []*common.ConfigRecord, preserveVersions bool) ([]*common.VersionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var imported []*common.VersionInfo
+
+	for _, record := range records {
+		s := m.services[service]
+
+		version := 1
+		if s != nil {
+			version = s.next
+		}
+
+		if preserveVersions {
+			if s != nil && s.versions[record.Version] != nil {
+				continue
+			}
+			version = record.Version
+		}
+
+		imported = append(imported, m.add(service, version, record.Data))
+
+		if service == m.failService {
+			m.failService = common.EMPTY_STRING
+			return imported, m.failImport
+		}
+	}
+
+	return imported, nil
+}
+
+// DropService function
+func (m *memBackend) DropService(ctx context.Context, service string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.services, service)
+
+	return nil
+}
+
+// Ping function
+func (m *memBackend) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close function
+func (m *memBackend) Close(ctx context.Context) error {
+	return nil
+}
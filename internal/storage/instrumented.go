@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/metrics"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER_NAME = "go-cloud-camp/internal/storage"
+
+// instrumentedBackend struct
+//
+// Обертка над StorageBackend, собирающая метрики времени выполнения
+// и ошибок, а также трассировку для каждого метода хранилища.
+type instrumentedBackend struct {
+	name    string
+	backend StorageBackend
+	metrics *metrics.Metrics
+	tracer  trace.Tracer
+}
+
+// newInstrumentedBackend function
+func newInstrumentedBackend(name string, backend StorageBackend, m *metrics.Metrics) *instrumentedBackend {
+	return &instrumentedBackend{
+		name:    name,
+		backend: backend,
+		metrics: m,
+		tracer:  otel.Tracer(TRACER_NAME),
+	}
+}
+
+// start function
+func (ib *instrumentedBackend) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	start := time.Now()
+
+	attrs = append(attrs, attribute.String("storage.backend", ib.name))
+	ctx, span := ib.tracer.Start(ctx, "StorageBackend."+method, trace.WithAttributes(attrs...))
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		if ib.metrics != nil {
+			ib.metrics.ObserveStorage(ib.name, method, time.Since(start), err)
+		}
+	}
+}
+
+// CreateConfig function
+func (ib *instrumentedBackend) CreateConfig(ctx context.Context, data *common.RequestData) (info *common.VersionInfo, err error) {
+	ctx, end := ib.start(ctx, "CreateConfig", attribute.String("config.service", data.Service))
+	defer func() { end(err) }()
+
+	return ib.backend.CreateConfig(ctx, data)
+}
+
+// ReadConfig function
+func (ib *instrumentedBackend) ReadConfig(ctx context.Context, service string, version int) (record *common.ConfigRecord, err error) {
+	ctx, end := ib.start(ctx, "ReadConfig", attribute.String("config.service", service), attribute.Int("config.version", version))
+	defer func() { end(err) }()
+
+	return ib.backend.ReadConfig(ctx, service, version)
+}
+
+// PeekConfig function
+func (ib *instrumentedBackend) PeekConfig(ctx context.Context, service string, version int) (record *common.ConfigRecord, err error) {
+	ctx, end := ib.start(ctx, "PeekConfig", attribute.String("config.service", service), attribute.Int("config.version", version))
+	defer func() { end(err) }()
+
+	return ib.backend.PeekConfig(ctx, service, version)
+}
+
+// UpdateConfig function
+func (ib *instrumentedBackend) UpdateConfig(ctx context.Context, data *common.RequestData) (info *common.VersionInfo, err error) {
+	ctx, end := ib.start(ctx, "UpdateConfig", attribute.String("config.service", data.Service))
+	defer func() { end(err) }()
+
+	return ib.backend.UpdateConfig(ctx, data)
+}
+
+// DeleteConfig function
+func (ib *instrumentedBackend) DeleteConfig(ctx context.Context, service string, version int) (err error) {
+	ctx, end := ib.start(ctx, "DeleteConfig", attribute.String("config.service", service), attribute.Int("config.version", version))
+	defer func() { end(err) }()
+
+	return ib.backend.DeleteConfig(ctx, service, version)
+}
+
+// ListServices function
+func (ib *instrumentedBackend) ListServices(ctx context.Context) (services []string, err error) {
+	ctx, end := ib.start(ctx, "ListServices")
+	defer func() { end(err) }()
+
+	return ib.backend.ListServices(ctx)
+}
+
+// ListVersions function
+func (ib *instrumentedBackend) ListVersions(ctx context.Context, service string) (versions []*common.VersionInfo, err error) {
+	ctx, end := ib.start(ctx, "ListVersions", attribute.String("config.service", service))
+	defer func() { end(err) }()
+
+	return ib.backend.ListVersions(ctx, service)
+}
+
+// CountVersions function
+func (ib *instrumentedBackend) CountVersions(ctx context.Context) (counts map[string]int, err error) {
+	ctx, end := ib.start(ctx, "CountVersions")
+	defer func() { end(err) }()
+
+	return ib.backend.CountVersions(ctx)
+}
+
+// RewriteConfigs function
+func (ib *instrumentedBackend) RewriteConfigs(ctx context.Context, service string, fn common.RewriteFunc) (err error) {
+	ctx, end := ib.start(ctx, "RewriteConfigs", attribute.String("config.service", service))
+	defer func() { end(err) }()
+
+	return ib.backend.RewriteConfigs(ctx, service, fn)
+}
+
+// ExportConfigs function
+func (ib *instrumentedBackend) ExportConfigs(ctx context.Context, service string) (records []*common.ConfigRecord, err error) {
+	ctx, end := ib.start(ctx, "ExportConfigs", attribute.String("config.service", service))
+	defer func() { end(err) }()
+
+	return ib.backend.ExportConfigs(ctx, service)
+}
+
+// ImportConfigs function
+func (ib *instrumentedBackend) ImportConfigs(ctx context.Context, service string, records []*common.ConfigRecord, preserveVersions bool) (imported []*common.VersionInfo, err error) {
+	ctx, end := ib.start(ctx, "ImportConfigs", attribute.String("config.service", service), attribute.Int("config.records", len(records)))
+	defer func() { end(err) }()
+
+	return ib.backend.ImportConfigs(ctx, service, records, preserveVersions)
+}
+
+// DropService function
+func (ib *instrumentedBackend) DropService(ctx context.Context, service string) (err error) {
+	ctx, end := ib.start(ctx, "DropService", attribute.String("config.service", service))
+	defer func() { end(err) }()
+
+	return ib.backend.DropService(ctx, service)
+}
+
+// Ping function
+func (ib *instrumentedBackend) Ping(ctx context.Context) (err error) {
+	ctx, end := ib.start(ctx, "Ping")
+	defer func() { end(err) }()
+
+	return ib.backend.Ping(ctx)
+}
+
+// Close function
+func (ib *instrumentedBackend) Close(ctx context.Context) (err error) {
+	ctx, end := ib.start(ctx, "Close")
+	defer func() { end(err) }()
+
+	return ib.backend.Close(ctx)
+}
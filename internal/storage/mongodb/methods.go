@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/logging"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,55 +15,49 @@ import (
 )
 
 // CreateConfig function
-func (mb *MongoBackend) CreateConfig(data *common.RequestData) error {
+func (mb *MongoBackend) CreateConfig(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error) {
 	collFilter := bson.D{{Key: "name", Value: data.Service}}
 
 	// Проверка, существует ли конфиг в базе данных
-	collList, err := mb.mdb.ListCollectionNames(context.Background(), collFilter)
+	collList, err := mb.mdb.ListCollectionNames(ctx, collFilter)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Если да, отправляем ошибку
 	if len(collList) > 0 {
-		return common.ErrAlreadyCreated
+		return nil, common.ErrAlreadyCreated
 	}
 
-	if err := mb.mdb.CreateCollection(context.Background(), data.Service); err != nil {
-		return err
+	if err := mb.mdb.CreateCollection(ctx, data.Service); err != nil {
+		return nil, err
 	}
 
+	logging.FromContext(ctx).Debugw("mongodb collection created", "service", data.Service)
+
 	coll := mb.mdb.Collection(data.Service)
 
 	counter := bson.D{
 		{Key: "_id", Value: "version_counter"},
 		{Key: "count", Value: 2},
 	}
-	if _, err := coll.InsertOne(context.Background(), counter); err != nil {
-		return err
+	if _, err := coll.InsertOne(ctx, counter); err != nil {
+		return nil, err
 	}
 
-	newConfig := &ConfigDataModel{
-		CreatedAt: time.Now(),
-		ReadedAt:  time.Now(),
-		Data:      data.Data,
-		Version:   1,
-	}
+	newConfig := newConfigDataModel(data.Service, 1, data.Data)
+	newConfig.ReadedAt = newConfig.CreatedAt
 
-	if _, err := coll.InsertOne(context.Background(), newConfig); err != nil {
-		return err
+	if _, err := coll.InsertOne(ctx, newConfig); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return newConfig.versionInfo(data.Service), nil
 }
 
 // ReadConfig function
-func (mb *MongoBackend) ReadConfig(service string, version int) ([]byte, error) {
-	// Если номер версии больше нуля, тогда добавляем в фильтр поиска
-	filter := primitive.D{}
-	if version > 0 {
-		filter = bson.D{{Key: "version", Value: version}}
-	}
+func (mb *MongoBackend) ReadConfig(ctx context.Context, service string, version int) (*common.ConfigRecord, error) {
+	filter := versionFilter(version)
 
 	// Обновляем время последнего обращения к конфигу
 	update := bson.D{{Key: "$currentDate", Value: bson.D{
@@ -73,7 +68,7 @@ func (mb *MongoBackend) ReadConfig(service string, version int) ([]byte, error)
 	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "version", Value: -1}})
 
 	coll := mb.mdb.Collection(service)
-	result := coll.FindOneAndUpdate(context.Background(), filter, update, opts)
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
 	if result.Err() != nil && errors.Is(result.Err(), mongo.ErrNoDocuments) {
 		return nil, common.ErrNotFound
 	}
@@ -85,11 +80,48 @@ func (mb *MongoBackend) ReadConfig(service string, version int) ([]byte, error)
 		return nil, err
 	}
 
-	return b.Data, nil
+	return &common.ConfigRecord{
+		VersionInfo: *b.versionInfo(service),
+		Data:        b.Data,
+	}, nil
+}
+
+// PeekConfig function
+//
+// Читает версию конфига, не обновляя время последнего обращения к нему.
+func (mb *MongoBackend) PeekConfig(ctx context.Context, service string, version int) (*common.ConfigRecord, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	b := &ConfigDataModel{}
+
+	err := mb.mdb.Collection(service).FindOne(ctx, versionFilter(version), opts).Decode(b)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, common.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.ConfigRecord{
+		VersionInfo: *b.versionInfo(service),
+		Data:        b.Data,
+	}, nil
+}
+
+// versionFilter function
+//
+// Если номер версии больше нуля, тогда добавляем его в фильтр поиска,
+// иначе ищем среди всех версий (кроме служебного счетчика версий).
+func versionFilter(version int) bson.D {
+	if version > 0 {
+		return bson.D{{Key: "version", Value: version}}
+	}
+
+	return bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
 }
 
 // UpdateConfig function
-func (mb *MongoBackend) UpdateConfig(data *common.RequestData) error {
+func (mb *MongoBackend) UpdateConfig(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error) {
 	// TODO
 	// Здесь используется два обращения к базе данных:
 	// инкремент счетчика версий и сохранение нового конфига в базу.
@@ -104,39 +136,87 @@ func (mb *MongoBackend) UpdateConfig(data *common.RequestData) error {
 	// некоторые номера версий просто будут пропущены
 
 	if !json.Valid(data.Data) {
-		return common.ErrNotValidJsonData
+		return nil, common.ErrNotValidJsonData
 	}
 
+	coll := mb.mdb.Collection(data.Service)
+
 	filterCounter := bson.D{{Key: "_id", Value: "version_counter"}}
 	updateCounter := bson.D{{Key: "$inc", Value: bson.D{{
 		Key: "count", Value: 1,
 	}}}}
 
-	resultCounter := mb.mdb.Collection(data.Service).FindOneAndUpdate(context.Background(), filterCounter, updateCounter)
+	// Условное обновление: счетчик увеличивается, только если
+	// с момента проверки последней версии не создано новых версий
+	if data.IfMatch != common.EMPTY_STRING {
+		count, err := mb.checkLatest(ctx, coll, data.Service, data.IfMatch)
+		if err != nil {
+			return nil, err
+		}
+		filterCounter = append(filterCounter, bson.E{Key: "count", Value: count})
+	}
+
+	resultCounter := coll.FindOneAndUpdate(ctx, filterCounter, updateCounter)
 	if resultCounter.Err() != nil {
-		return resultCounter.Err()
+		if errors.Is(resultCounter.Err(), mongo.ErrNoDocuments) {
+			if data.IfMatch != common.EMPTY_STRING {
+				return nil, common.ErrPreconditionFailed
+			}
+			// Счетчик версий создается вместе с конфигом сервиса
+			return nil, common.ErrServiceNotFound
+		}
+		return nil, resultCounter.Err()
 	}
 
 	version := &CounterModel{}
 	if err := resultCounter.Decode(version); err != nil {
-		return err
+		return nil, err
 	}
 
-	newConfig := &ConfigDataModel{
-		Version:   version.Count,
-		Data:      data.Data,
-		CreatedAt: time.Now(),
+	newConfig := newConfigDataModel(data.Service, version.Count, data.Data)
+
+	if _, err := coll.InsertOne(ctx, newConfig); err != nil {
+		return nil, err
 	}
 
-	if _, err := mb.mdb.Collection(data.Service).InsertOne(context.Background(), newConfig); err != nil {
-		return err
+	return newConfig.versionInfo(data.Service), nil
+}
+
+// checkLatest function
+//
+// Проверяет, что ETag последней версии конфига равен etag, и возвращает
+// значение счетчика версий, прочитанное до проверки.
+func (mb *MongoBackend) checkLatest(ctx context.Context, coll *mongo.Collection, service string, etag string) (int, error) {
+	counter := &CounterModel{}
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: "version_counter"}}).Decode(counter)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, common.ErrServiceNotFound
+	}
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	latest := &ConfigDataModel{}
+	err = coll.FindOne(ctx, filter, opts).Decode(latest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, common.ErrPreconditionFailed
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if latest.etag(service) != etag {
+		return 0, common.ErrPreconditionFailed
+	}
+
+	return counter.Count, nil
 }
 
 // DeleteConfig function
-func (mb *MongoBackend) DeleteConfig(service string, version int) error {
+func (mb *MongoBackend) DeleteConfig(ctx context.Context, service string, version int) error {
 	filter := primitive.D{}
 	if version > 0 {
 		filter = bson.D{{Key: "version", Value: version}}
@@ -146,10 +226,14 @@ func (mb *MongoBackend) DeleteConfig(service string, version int) error {
 
 	coll := mb.mdb.Collection(service)
 
-	findResult := coll.FindOne(context.Background(), filter, nil, opts)
+	findResult := coll.FindOne(ctx, filter, nil, opts)
 	if findResult.Err() != nil {
 		if findResult.Err() == mongo.ErrNoDocuments {
-			return coll.Drop(context.Background())
+			// Удаление несуществующей версии не должно удалять весь сервис
+			if version > 0 {
+				return common.ErrNotFound
+			}
+			return coll.Drop(ctx)
 		} else {
 			return findResult.Err()
 		}
@@ -166,9 +250,9 @@ func (mb *MongoBackend) DeleteConfig(service string, version int) error {
 
 	var deleteErr error
 	if version == 0 {
-		deleteErr = coll.Drop(context.Background())
+		deleteErr = coll.Drop(ctx)
 	} else {
-		_, deleteErr = coll.DeleteOne(context.Background(), filter)
+		_, deleteErr = coll.DeleteOne(ctx, filter)
 	}
 	if deleteErr != nil {
 		return deleteErr
@@ -176,3 +260,245 @@ func (mb *MongoBackend) DeleteConfig(service string, version int) error {
 
 	return nil
 }
+
+// ListServices function
+func (mb *MongoBackend) ListServices(ctx context.Context) ([]string, error) {
+	return mb.mdb.ListCollectionNames(ctx, bson.D{})
+}
+
+// ListVersions function
+func (mb *MongoBackend) ListVersions(ctx context.Context, service string) ([]*common.VersionInfo, error) {
+	coll := mb.mdb.Collection(service)
+
+	// Служебный документ счетчика версий не содержит поля version
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var versions []*common.VersionInfo
+
+	for cursor.Next(ctx) {
+		configData := &ConfigDataModel{}
+		if err := cursor.Decode(configData); err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, configData.versionInfo(service))
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, common.ErrServiceNotFound
+	}
+
+	return versions, nil
+}
+
+// RewriteConfigs function
+func (mb *MongoBackend) RewriteConfigs(ctx context.Context, service string, fn common.RewriteFunc) error {
+	coll := mb.mdb.Collection(service)
+
+	// Служебный документ счетчика версий не содержит поля version
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
+
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		configData := &ConfigDataModel{}
+		if err := cursor.Decode(configData); err != nil {
+			return err
+		}
+
+		newData, changed, err := fn(configData.Data)
+		if err != nil {
+			return err
+		}
+
+		if !changed {
+			continue
+		}
+
+		// ETag сохраняется по исходным данным, чтобы перешифровка
+		// не меняла ETag версии
+		update := bson.D{{Key: "$set", Value: bson.D{
+			{Key: "data", Value: newData},
+			{Key: "etag", Value: configData.etag(service)},
+		}}}
+		if _, err := coll.UpdateByID(ctx, configData.ID, update); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// CountVersions function
+func (mb *MongoBackend) CountVersions(ctx context.Context) (map[string]int, error) {
+	services, err := mb.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
+
+	result := make(map[string]int, len(services))
+	for _, service := range services {
+		count, err := mb.mdb.Collection(service).CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		result[service] = int(count)
+	}
+
+	return result, nil
+}
+
+// ExportConfigs function
+//
+// Все версии конфига сервиса по возрастанию номера версии. В отличие
+// от ReadConfig время последнего обращения к версиям не обновляется.
+func (mb *MongoBackend) ExportConfigs(ctx context.Context, service string) ([]*common.ConfigRecord, error) {
+	coll := mb.mdb.Collection(service)
+
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*common.ConfigRecord
+
+	for cursor.Next(ctx) {
+		configData := &ConfigDataModel{}
+		if err := cursor.Decode(configData); err != nil {
+			return nil, err
+		}
+
+		records = append(records, &common.ConfigRecord{
+			VersionInfo: *configData.versionInfo(service),
+			Data:        configData.Data,
+		})
+	}
+
+	return records, cursor.Err()
+}
+
+// ImportConfigs function
+//
+// Сохраняет версии конфига сервиса из архива. При preserveVersions
+// версии сохраняются с исходными номерами, а версии с уже занятыми
+// номерами пропускаются. Иначе версии получают следующие номера
+// по счетчику версий сервиса.
+func (mb *MongoBackend) ImportConfigs(ctx context.Context, service string, records []*common.ConfigRecord, preserveVersions bool) ([]*common.VersionInfo, error) {
+	// TODO
+	// Как и в UpdateConfig, запросы выполняются без транзакции,
+	// поэтому загрузку не следует совмещать с записью конфигов сервиса
+
+	coll := mb.mdb.Collection(service)
+
+	filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}}
+	opts := options.Find().SetProjection(bson.D{{Key: "version", Value: 1}})
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[int]bool{}
+	next := 1
+
+	for cursor.Next(ctx) {
+		configData := &ConfigDataModel{}
+		if err := cursor.Decode(configData); err != nil {
+			cursor.Close(ctx)
+			return nil, err
+		}
+
+		existing[configData.Version] = true
+		if configData.Version >= next {
+			next = configData.Version + 1
+		}
+	}
+	cursor.Close(ctx)
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// Номера удаленных версий не используются повторно
+	counter := &CounterModel{}
+	err = coll.FindOne(ctx, bson.D{{Key: "_id", Value: "version_counter"}}).Decode(counter)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if counter.Count > next {
+		next = counter.Count
+	}
+
+	var imported []*common.VersionInfo
+
+	for _, record := range records {
+		version := next
+		if preserveVersions {
+			if existing[record.Version] {
+				continue
+			}
+			version = record.Version
+		}
+
+		newConfig := newConfigDataModel(service, version, record.Data)
+		if !record.CreatedAt.IsZero() {
+			newConfig.CreatedAt = record.CreatedAt.UTC()
+		}
+
+		// Версия с исходным номером сохраняет ETag из архива
+		if version == record.Version && record.ETag != common.EMPTY_STRING {
+			newConfig.ETag = record.ETag
+		}
+
+		if _, err := coll.InsertOne(ctx, newConfig); err != nil {
+			return imported, err
+		}
+
+		existing[version] = true
+		if version >= next {
+			next = version + 1
+		}
+
+		imported = append(imported, newConfig.versionInfo(service))
+	}
+
+	filterCounter := bson.D{{Key: "_id", Value: "version_counter"}}
+	updateCounter := bson.D{{Key: "$max", Value: bson.D{{
+		Key: "count", Value: next,
+	}}}}
+
+	if _, err := coll.UpdateOne(ctx, filterCounter, updateCounter, options.Update().SetUpsert(true)); err != nil {
+		return imported, err
+	}
+
+	return imported, nil
+}
+
+// DropService function
+//
+// Удаляет все версии конфига сервиса без проверки времени
+// последнего обращения.
+func (mb *MongoBackend) DropService(ctx context.Context, service string) error {
+	return mb.mdb.Collection(service).Drop(ctx)
+}
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"encoding/json"
+	"go-cloud-camp/internal/common"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,6 +15,41 @@ type ConfigDataModel struct {
 	CreatedAt time.Time          `bson:"createdAt"`
 	ReadedAt  time.Time          `bson:"readedAt"`
 	Data      json.RawMessage    `bson:"data"`
+
+	// ETag вычисляется при сохранении версии и не меняется при
+	// перешифровке секретных значений
+	ETag string `bson:"etag,omitempty"`
+}
+
+// newConfigDataModel function
+func newConfigDataModel(service string, version int, data json.RawMessage) *ConfigDataModel {
+	return &ConfigDataModel{
+		Version:   version,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+		ETag:      common.ETag(service, version, data),
+	}
+}
+
+// etag function
+//
+// Для версий, сохраненных без ETag, он вычисляется по данным.
+func (m *ConfigDataModel) etag(service string) string {
+	if m.ETag != "" {
+		return m.ETag
+	}
+
+	return common.ETag(service, m.Version, m.Data)
+}
+
+// versionInfo function
+func (m *ConfigDataModel) versionInfo(service string) *common.VersionInfo {
+	return &common.VersionInfo{
+		Service:   service,
+		Version:   m.Version,
+		CreatedAt: m.CreatedAt,
+		ETag:      m.etag(service),
+	}
 }
 
 // CounterModel struct
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 // MongBackend struct
@@ -27,7 +28,12 @@ func Create(cfg *config.StorageParams, logger *logging.Logger) (*MongoBackend, e
 		cfg.MongoDB.MaxPoolSize,
 	)
 
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoUri))
+	// Трассировка команд MongoDB
+	clientOpts := options.Client().
+		ApplyURI(mongoUri).
+		SetMonitor(otelmongo.NewMonitor())
+
+	client, err := mongo.Connect(context.Background(), clientOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -50,3 +56,8 @@ func Create(cfg *config.StorageParams, logger *logging.Logger) (*MongoBackend, e
 func (mb *MongoBackend) Close(ctx context.Context) error {
 	return mb.client.Disconnect(ctx)
 }
+
+// Ping function
+func (mb *MongoBackend) Ping(ctx context.Context) error {
+	return mb.client.Ping(ctx, readpref.Primary())
+}
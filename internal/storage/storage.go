@@ -2,19 +2,33 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"go-cloud-camp/internal/common"
 	"go-cloud-camp/internal/config"
 	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/metrics"
+	"go-cloud-camp/internal/redact"
+	"go-cloud-camp/internal/secrets"
 	"go-cloud-camp/internal/storage/mongodb"
 	"log"
+	"time"
 )
 
 // StorageBackend interface
 type StorageBackend interface {
-	CreateConfig(data *common.RequestData) error
-	ReadConfig(string, int) ([]byte, error)
-	UpdateConfig(data *common.RequestData) error
-	DeleteConfig(string, int) error
+	CreateConfig(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error)
+	ReadConfig(context.Context, string, int) (*common.ConfigRecord, error)
+	PeekConfig(context.Context, string, int) (*common.ConfigRecord, error)
+	UpdateConfig(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error)
+	DeleteConfig(context.Context, string, int) error
+	ListServices(context.Context) ([]string, error)
+	ListVersions(context.Context, string) ([]*common.VersionInfo, error)
+	CountVersions(context.Context) (map[string]int, error)
+	RewriteConfigs(context.Context, string, common.RewriteFunc) error
+	ExportConfigs(context.Context, string) ([]*common.ConfigRecord, error)
+	ImportConfigs(ctx context.Context, service string, records []*common.ConfigRecord, preserveVersions bool) ([]*common.VersionInfo, error)
+	DropService(context.Context, string) error
+	Ping(context.Context) error
 	Close(context.Context) error
 }
 
@@ -24,12 +38,16 @@ const (
 
 // AppStorage struct
 type AppStorage struct {
-	logger  *logging.Logger
-	backend StorageBackend
+	logger   *logging.Logger
+	backend  StorageBackend
+	keyring  *secrets.Keyring
+	redactor *redact.Policy
+	watchers watchers
+	done     chan struct{}
 }
 
 // Create function
-func Create(cfg *config.StorageParams, log *logging.Logger) (*AppStorage, error) {
+func Create(cfg *config.StorageParams, keyring *secrets.Keyring, redactor *redact.Policy, m *metrics.Metrics, log *logging.Logger) (*AppStorage, error) {
 	var err error
 	var backend StorageBackend
 
@@ -44,35 +62,255 @@ func Create(cfg *config.StorageParams, log *logging.Logger) (*AppStorage, error)
 		return nil, err
 	}
 
+	backend = newInstrumentedBackend(cfg.Backend, backend, m)
+
+	if m != nil {
+		m.RegisterStorage(cfg.Backend, func() (map[string]int, error) {
+			return backend.CountVersions(context.Background())
+		})
+	}
+
 	return &AppStorage{
-		logger:  log,
-		backend: backend,
+		logger:   log,
+		backend:  backend,
+		keyring:  keyring,
+		redactor: redactor,
+		done:     make(chan struct{}),
 	}, nil
 }
 
 // Close function
 func (s *AppStorage) Close() {
+	close(s.done)
+
 	if err := s.backend.Close(context.Background()); err != nil {
 		log.Fatalln(err)
 	}
 }
 
 // Create function
-func (s *AppStorage) Create(data *common.RequestData) error {
-	return s.backend.CreateConfig(data)
+func (s *AppStorage) Create(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error) {
+	var err error
+	if data.Data, err = s.sealSecrets(ctx, data); err != nil {
+		return nil, err
+	}
+
+	info, err := s.backend.CreateConfig(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(info)
+
+	return info, nil
 }
 
 // Read function
-func (s *AppStorage) Read(service string, version int) ([]byte, error) {
-	return s.backend.ReadConfig(service, version)
+func (s *AppStorage) Read(ctx context.Context, service string, version int, revealSecrets bool) (*common.ConfigRecord, error) {
+	record, err := s.backend.ReadConfig(ctx, service, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Data, err = s.reveal(record.Data, revealSecrets); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ReadMarked function
+//
+// Читает версию конфига с открытыми секретными значениями в маркерах
+// {"$secret": value}. Такой конфиг можно сохранить как новую версию,
+// например при откате к предыдущей версии, без потери секретов.
+func (s *AppStorage) ReadMarked(ctx context.Context, service string, version int) (*common.ConfigRecord, error) {
+	record, err := s.backend.ReadConfig(ctx, service, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openMarked(record)
+}
+
+// PeekMarked function
+//
+// Читает версию конфига, как ReadMarked, но не отмечает ее как
+// прочитанную. Используется при синхронизации с каталогом, чтобы
+// регулярные сравнения не мешали удалению версий.
+func (s *AppStorage) PeekMarked(ctx context.Context, service string, version int) (*common.ConfigRecord, error) {
+	record, err := s.backend.PeekConfig(ctx, service, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openMarked(record)
+}
+
+// openMarked function
+func (s *AppStorage) openMarked(record *common.ConfigRecord) (*common.ConfigRecord, error) {
+	var err error
+
+	if !secrets.HasMarker(record.Data, secrets.ENCRYPTED_MARKER) {
+		return record, nil
+	}
+
+	if s.keyring == nil {
+		return nil, common.ErrSecretsDisabled
+	}
+
+	if record.Data, err = s.keyring.OpenMarked(record.Data); err != nil {
+		return nil, err
+	}
+
+	return record, nil
 }
 
 // Update function
-func (s *AppStorage) Update(data *common.RequestData) error {
-	return s.backend.UpdateConfig(data)
+//
+// При заданном data.IfMatch новая версия создается, только если ETag
+// последней версии конфига совпадает с ним, иначе возвращается
+// common.ErrPreconditionFailed.
+func (s *AppStorage) Update(ctx context.Context, data *common.RequestData) (*common.VersionInfo, error) {
+	var err error
+	if data.Data, err = s.sealSecrets(ctx, data); err != nil {
+		return nil, err
+	}
+
+	info, err := s.backend.UpdateConfig(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(info)
+
+	return info, nil
 }
 
 // Delete function
-func (s *AppStorage) Delete(service string, version int) error {
-	return s.backend.DeleteConfig(service, version)
+func (s *AppStorage) Delete(ctx context.Context, service string, version int) error {
+	return s.backend.DeleteConfig(ctx, service, version)
+}
+
+// List function
+func (s *AppStorage) List(ctx context.Context) ([]string, error) {
+	return s.backend.ListServices(ctx)
+}
+
+// Versions function
+//
+// Сведения о всех версиях конфига сервиса по возрастанию номера версии.
+func (s *AppStorage) Versions(ctx context.Context, service string) ([]*common.VersionInfo, error) {
+	return s.backend.ListVersions(ctx, service)
+}
+
+// Ping function
+func (s *AppStorage) Ping(ctx context.Context) error {
+	return s.backend.Ping(ctx)
+}
+
+// StartKeyRotation function
+//
+// В отдельной горутине периодически перечитывает файл ключей и
+// перешифровывает основным ключом все сохраненные версии конфигов.
+func (s *AppStorage) StartKeyRotation(interval time.Duration) {
+	if s.keyring == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.rotateKeys()
+
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.keyring.Reload(); err != nil {
+					s.logger.Errorw("couldn't reload secrets keyring", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// rotateKeys function
+func (s *AppStorage) rotateKeys() {
+	ctx := context.Background()
+
+	services, err := s.backend.ListServices(ctx)
+	if err != nil {
+		s.logger.Errorw("key rotation aborted with error", "error", err)
+		return
+	}
+
+	for _, service := range services {
+		err := s.backend.RewriteConfigs(ctx, service, func(data json.RawMessage) (json.RawMessage, bool, error) {
+			newData, changed, err := s.keyring.Reencrypt(data)
+			if err != nil {
+				// Поврежденная версия не должна останавливать ротацию
+				// остальных версий сервиса
+				s.logger.Errorw("couldn't reencrypt config version, skipped", "service", service, "error", err)
+				return data, false, nil
+			}
+
+			return newData, changed, nil
+		})
+		if err != nil {
+			s.logger.Errorw("key rotation aborted with error", "service", service, "error", err)
+		}
+	}
+
+	s.logger.Debugw("key rotation completed", "primary_key", s.keyring.Primary())
+}
+
+// reveal function
+func (s *AppStorage) reveal(data json.RawMessage, revealSecrets bool) (json.RawMessage, error) {
+	// Клиентам без доступа к секретам возвращаем конфиг с замаскированными
+	// секретными значениями и значениями чувствительных ключей
+	if !revealSecrets {
+		return s.redactor.JSON(data)
+	}
+
+	if !secrets.HasSecrets(data) {
+		return data, nil
+	}
+
+	if s.keyring == nil {
+		return secrets.Redact(data)
+	}
+
+	return s.keyring.Open(data)
+}
+
+// sealSecrets function
+func (s *AppStorage) sealSecrets(ctx context.Context, data *common.RequestData) (json.RawMessage, error) {
+	if !secrets.HasSecrets(data.Data) {
+		return data.Data, nil
+	}
+
+	if !json.Valid(data.Data) {
+		return nil, common.ErrNotValidJsonData
+	}
+
+	// Зашифрованные значения создает только сервер, принятые
+	// от клиента значения нельзя было бы прочитать или перешифровать
+	if secrets.HasMarker(data.Data, secrets.ENCRYPTED_MARKER) {
+		return nil, common.NewValidationError(common.ErrInvalidRequest, "data",
+			"must not contain "+secrets.ENCRYPTED_MARKER+" values")
+	}
+
+	if s.keyring == nil {
+		return nil, common.ErrSecretsDisabled
+	}
+
+	logging.FromContext(ctx).Debugw("seal secret values",
+		"service", data.Service,
+		"key_id", s.keyring.Primary(),
+	)
+
+	return s.keyring.Seal(data.Data)
 }
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-cloud-camp/internal/common"
+	"go-cloud-camp/internal/secrets"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateRejectsEncryptedValues(t *testing.T) {
+	s, _ := newTestStorage(t, testKeyring(t))
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, &common.RequestData{
+		Service: "svc",
+		Data:    json.RawMessage(`{"password":{"$encrypted":{"kid":"k1","key":"AA==","data":"AA=="}}}`),
+	})
+	if !errors.Is(err, common.ErrInvalidRequest) {
+		t.Fatalf("Create() error = %v, want %v", err, common.ErrInvalidRequest)
+	}
+
+	info, err := s.Create(ctx, &common.RequestData{
+		Service: "svc",
+		Data:    json.RawMessage(`{"password":{"$secret":"p"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := s.Read(ctx, "svc", info.Version, true)
+	if err != nil || string(record.Data) != `{"password":"p"}` {
+		t.Fatalf("Read() = %s, %v", record.Data, err)
+	}
+}
+
+func TestRotateKeysSkipsBrokenVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yml")
+	writeKeyFile(t, path, "k1")
+
+	kr, err := secrets.LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, backend := newTestStorage(t, kr)
+
+	sealed, err := kr.Seal(json.RawMessage(`{"token":{"$secret":"t"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Версия, зашифрованная неизвестным ключом, не должна прерывать
+	// перешифровку следующих версий
+	broken := json.RawMessage(`{"token":{"$encrypted":{"kid":"gone","key":"AA==","data":"AA=="}}}`)
+	backend.add("svc", 1, broken)
+	backend.add("svc", 2, sealed)
+
+	writeKeyFile(t, path, "k2")
+	if err := kr.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.rotateKeys()
+
+	ctx := context.Background()
+
+	record, err := backend.ReadConfig(ctx, "svc", 1)
+	if err != nil || string(record.Data) != string(broken) {
+		t.Fatalf("broken version is changed: %s, %v", record.Data, err)
+	}
+
+	record, err = backend.ReadConfig(ctx, "svc", 2)
+	if err != nil || !strings.Contains(string(record.Data), `"kid":"k2"`) {
+		t.Fatalf("version is not reencrypted: %s, %v", record.Data, err)
+	}
+}
+
+func TestReadMarkedRoundTrip(t *testing.T) {
+	s, backend := newTestStorage(t, testKeyring(t))
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, &common.RequestData{
+		Service: "svc",
+		Data:    json.RawMessage(`{"user":"app","password":{"$secret":"p"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := s.ReadMarked(ctx, "svc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(record.Data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	marker, value, ok := secrets.Marker(doc["password"])
+	if !ok || marker != secrets.SECRET_MARKER || value != "p" {
+		t.Fatalf("ReadMarked() = %s, want secret marker", record.Data)
+	}
+
+	// Прочитанную конфигурацию можно записать обратно без потери секретов
+	_, err = s.Update(ctx, &common.RequestData{Service: "svc", Data: record.Data, IfMatch: created.ETag})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := backend.ReadConfig(ctx, "svc", 0)
+	if err != nil || !strings.Contains(string(stored.Data), secrets.ENCRYPTED_MARKER) {
+		t.Fatalf("secret is not encrypted: %s, %v", stored.Data, err)
+	}
+
+	record, err = s.Read(ctx, "svc", 0, true)
+	if err != nil || string(record.Data) != `{"password":"p","user":"app"}` {
+		t.Fatalf("Read() = %s, %v", record.Data, err)
+	}
+}
+
+func TestReadMarkedWithoutKeyring(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	backend.add("plain", 1, json.RawMessage(`{"user":"app"}`))
+	backend.add("sealed", 1, json.RawMessage(`{"token":{"$encrypted":{"kid":"k1","key":"AA==","data":"AA=="}}}`))
+
+	record, err := s.ReadMarked(ctx, "plain", 0)
+	if err != nil || string(record.Data) != `{"user":"app"}` {
+		t.Fatalf("ReadMarked() = %v, %v", record, err)
+	}
+
+	if _, err := s.ReadMarked(ctx, "sealed", 0); !errors.Is(err, common.ErrSecretsDisabled) {
+		t.Fatalf("ReadMarked() error = %v, want %v", err, common.ErrSecretsDisabled)
+	}
+}
+
+func TestPeekMarkedDoesNotMarkRead(t *testing.T) {
+	s, backend := newTestStorage(t, testKeyring(t))
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, &common.RequestData{Service: "svc", Data: json.RawMessage(`{"token":{"$secret":"t"}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := s.PeekMarked(ctx, "svc", 0)
+	if err != nil || string(record.Data) != `{"token":{"$secret":"t"}}` {
+		t.Fatalf("PeekMarked() = %v, %v", record, err)
+	}
+
+	// Прочитанные версии хранилище не дает удалить некоторое время
+	if backend.reads["svc"] != 0 {
+		t.Fatalf("PeekMarked() marked the version as read")
+	}
+
+	if _, err := s.ReadMarked(ctx, "svc", 0); err != nil || backend.reads["svc"] != 1 {
+		t.Fatalf("ReadMarked() reads = %d, %v", backend.reads["svc"], err)
+	}
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	s, _ := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	first, err := s.Create(ctx, &common.RequestData{Service: "svc", Data: json.RawMessage(`{"a":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Update(ctx, &common.RequestData{Service: "svc", Data: json.RawMessage(`{"a":2}`), IfMatch: first.ETag})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ETag первой версии устарел после создания второй
+	_, err = s.Update(ctx, &common.RequestData{Service: "svc", Data: json.RawMessage(`{"a":3}`), IfMatch: first.ETag})
+	if !errors.Is(err, common.ErrPreconditionFailed) {
+		t.Fatalf("Update() error = %v, want %v", err, common.ErrPreconditionFailed)
+	}
+
+	record, err := s.Read(ctx, "svc", 0, false)
+	if err != nil || record.Version != second.Version {
+		t.Fatalf("Read() = %v, %v, want version %d", record, err, second.Version)
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"go-cloud-camp/internal/common"
+	"sync"
+)
+
+// Размер буфера уведомлений одного подписчика
+const WATCH_BUFFER_SIZE = 16
+
+// watchers struct
+//
+// Подписчики на новые версии конфигов в пределах одного экземпляра сервера.
+type watchers struct {
+	mu      sync.Mutex
+	stopped bool
+	subs    map[string]map[chan *common.VersionInfo]struct{}
+}
+
+// Watch function
+//
+// Подписывает на новые версии конфига сервиса. Возвращает канал уведомлений
+// и функцию отмены подписки. Канал закрывается при отмене подписки или
+// остановке уведомлений. Если подписчик не успевает читать уведомления,
+// промежуточные версии пропускаются.
+func (s *AppStorage) Watch(service string) (<-chan *common.VersionInfo, func()) {
+	ch := make(chan *common.VersionInfo, WATCH_BUFFER_SIZE)
+
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+
+	if s.watchers.stopped {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if s.watchers.subs == nil {
+		s.watchers.subs = map[string]map[chan *common.VersionInfo]struct{}{}
+	}
+	if s.watchers.subs[service] == nil {
+		s.watchers.subs[service] = map[chan *common.VersionInfo]struct{}{}
+	}
+	s.watchers.subs[service][ch] = struct{}{}
+
+	cancel := func() {
+		s.watchers.mu.Lock()
+		defer s.watchers.mu.Unlock()
+
+		if _, ok := s.watchers.subs[service][ch]; !ok {
+			return
+		}
+
+		delete(s.watchers.subs[service], ch)
+		if len(s.watchers.subs[service]) == 0 {
+			delete(s.watchers.subs, service)
+		}
+		close(ch)
+	}
+
+	return ch, cancel
+}
+
+// StopWatch function
+//
+// Закрывает все подписки и отклоняет новые, вызывается при остановке сервера.
+func (s *AppStorage) StopWatch() {
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+
+	s.watchers.stopped = true
+
+	for _, subs := range s.watchers.subs {
+		for ch := range subs {
+			close(ch)
+		}
+	}
+	s.watchers.subs = nil
+}
+
+// publish function
+func (s *AppStorage) publish(info *common.VersionInfo) {
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+
+	for ch := range s.watchers.subs[info.Service] {
+		select {
+		case ch <- info:
+		default:
+			s.logger.Debugw("watch subscriber is too slow, version skipped",
+				"service", info.Service,
+				"version", info.Version,
+			)
+		}
+	}
+}
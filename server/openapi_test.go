@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/json"
+	"go-cloud-camp/internal/auth"
+	"go-cloud-camp/internal/config"
+	"go-cloud-camp/internal/handlers"
+	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/metrics"
+	"go-cloud-camp/internal/ratelimit"
+	"go-cloud-camp/internal/redact"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+// expectedRoute struct
+//
+// Маршрут, который должен обрабатывать роутер: путь в спецификации
+// и пример пути запроса к нему.
+type expectedRoute struct {
+	method string
+	path   string
+	target string
+
+	// Маршрут описан в спецификации OpenAPI
+	described bool
+}
+
+// Маршруты сервера. Метрики отдаются в формате Prometheus, а не JSON API,
+// поэтому не описаны в спецификации.
+var expectedRoutes = []expectedRoute{
+	{http.MethodGet, "/config", "/config?service=svc", true},
+	{http.MethodPost, "/config", "/config", true},
+	{http.MethodPut, "/config", "/config", true},
+	{http.MethodDelete, "/config", "/config?service=svc", true},
+
+	{http.MethodGet, "/v1/services", "/v1/services", true},
+	{http.MethodGet, "/v1/services/{service}/config", "/v1/services/svc/config", true},
+	{http.MethodPost, "/v1/services/{service}/config", "/v1/services/svc/config", true},
+	{http.MethodPut, "/v1/services/{service}/config", "/v1/services/svc/config", true},
+	{http.MethodDelete, "/v1/services/{service}/config", "/v1/services/svc/config", true},
+	{http.MethodGet, "/v1/services/{service}/versions", "/v1/services/svc/versions", true},
+	{http.MethodGet, "/v1/services/{service}/versions/{version}", "/v1/services/svc/versions/2", true},
+	{http.MethodDelete, "/v1/services/{service}/versions/{version}", "/v1/services/svc/versions/2", true},
+
+	{http.MethodGet, "/admin/export", "/admin/export", true},
+	{http.MethodPost, "/admin/import", "/admin/import", true},
+
+	{http.MethodGet, "/healthz", "/healthz", true},
+	{http.MethodGet, "/readyz", "/readyz", true},
+	{http.MethodGet, "/openapi.json", "/openapi.json", true},
+
+	{http.MethodGet, "/metrics", "/metrics", false},
+}
+
+// testServer function
+//
+// Сервер с роутером, на котором маршруты регистрируются так же, как
+// в Create: маршруты API и метрики без отдельного порта.
+func testServer(t *testing.T) *ConfigServer {
+	t.Helper()
+
+	log := &logging.Logger{SugaredLogger: zap.NewNop().Sugar()}
+
+	s := &ConfigServer{
+		cfg: &config.Config{
+			Metrics: config.MetricsParams{Enabled: true, Path: "/metrics"},
+		},
+		log:     log,
+		metrics: metrics.Create(),
+		router:  httprouter.New(),
+	}
+
+	s.handlers = handlers.Create(log, nil, auth.Create(&config.AuthParams{}), redact.Create(&config.RedactionParams{}),
+		ratelimit.Create(&config.LimitsParams{}), s.metrics)
+	s.handlers.Register(s.router)
+
+	if err := s.createMetricsServer(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	s := testServer(t)
+	spec := s.handlers.Spec()
+
+	expected := map[string]bool{}
+	for _, rt := range expectedRoutes {
+		path := strings.SplitN(rt.target, "?", 2)[0]
+		if handle, _, _ := s.router.Lookup(rt.method, path); handle == nil {
+			t.Errorf("route %s %s is not routed", rt.method, rt.target)
+		}
+
+		if !rt.described {
+			continue
+		}
+		expected[rt.method+" "+rt.path] = true
+
+		if spec.Paths[rt.path][strings.ToLower(rt.method)] == nil {
+			t.Errorf("route %s %s is not described in the openapi specification", rt.method, rt.path)
+		}
+	}
+
+	for path, item := range spec.Paths {
+		for method := range item {
+			if operation := strings.ToUpper(method) + " " + path; !expected[operation] {
+				t.Errorf("operation %s is described but not expected", operation)
+			}
+		}
+	}
+}
+
+func TestRouteMethods(t *testing.T) {
+	s := testServer(t)
+
+	methods := map[string][]string{}
+	for _, rt := range expectedRoutes {
+		path := strings.SplitN(rt.target, "?", 2)[0]
+		methods[path] = append(methods[path], rt.method)
+	}
+
+	// Роутер перечисляет методы пути в заголовке Allow ответа на OPTIONS
+	for path, allowed := range methods {
+		allowed = append(allowed, http.MethodOptions)
+		sort.Strings(allowed)
+
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, path, nil))
+
+		if got, want := w.Header().Get("Allow"), strings.Join(allowed, ", "); got != want {
+			t.Errorf("OPTIONS %s: Allow = %q, want %q", path, got, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/config", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PATCH /config: status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/configs", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /v1/configs: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestPublicRoutes(t *testing.T) {
+	s := testServer(t)
+
+	for _, path := range []string{"/healthz", "/openapi.json", "/metrics"} {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+
+	// Обработчик отдает ту же спецификацию, что проверяется выше
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var doc struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Paths) != len(s.handlers.Spec().Paths) {
+		t.Errorf("served specification has %d paths, want %d", len(doc.Paths), len(s.handlers.Spec().Paths))
+	}
+}
@@ -4,27 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go-cloud-camp/internal/auth"
+	"go-cloud-camp/internal/common"
 	"go-cloud-camp/internal/config"
+	"go-cloud-camp/internal/configsync"
+	"go-cloud-camp/internal/grpcapi"
 	"go-cloud-camp/internal/handlers"
 	"go-cloud-camp/internal/logging"
+	"go-cloud-camp/internal/metrics"
+	"go-cloud-camp/internal/ratelimit"
+	"go-cloud-camp/internal/redact"
+	"go-cloud-camp/internal/secrets"
 	"go-cloud-camp/internal/storage"
+	"go-cloud-camp/internal/tracing"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
 )
 
 // ConfigServer struct
 type ConfigServer struct {
 	cfg      *config.Config
 	log      *logging.Logger
+	keyring  *secrets.Keyring
+	redactor *redact.Policy
+	limits   *ratelimit.Limiter
+	metrics  *metrics.Metrics
 	storage  *storage.AppStorage
+	handlers *handlers.AppHandlers
 	router   *httprouter.Router
 	listener net.Listener
 	server   *http.Server
+	tracing  tracing.ShutdownFunc
+
+	// Синхронизация конфигураций с каталогом, если каталог задан
+	syncer *configsync.Syncer
+
+	// Отдельный сервер метрик, если для метрик задан свой порт
+	metricsListener net.Listener
+	metricsServer   *http.Server
+
+	// Сервер gRPC, без отдельного порта работает на основном порту
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+	grpcCalls    grpcCalls
 }
 
 // Create function
@@ -43,20 +76,73 @@ func Create(path string) (*ConfigServer, error) {
 		return nil, err
 	}
 
+	// Setup tracing
+	if srv.tracing, err = tracing.Setup(&srv.cfg.Tracing); err != nil {
+		return nil, err
+	}
+
+	// Load secrets keyring
+	if srv.cfg.Secrets.KeyFile != common.EMPTY_STRING {
+		if srv.keyring, err = secrets.LoadKeyring(srv.cfg.Secrets.KeyFile); err != nil {
+			return nil, err
+		}
+
+		if srv.cfg.Secrets.RotationInterval <= 0 {
+			return nil, fmt.Errorf("secrets.rotation_interval must be positive, got %s", srv.cfg.Secrets.RotationInterval)
+		}
+	}
+
+	// Create redaction policy
+	srv.redactor = redact.Create(&srv.cfg.Redaction)
+
+	// Create metrics
+	srv.metrics = metrics.Create()
+
 	// Create storage
-	if srv.storage, err = storage.Create(&srv.cfg.Storage, srv.log); err != nil {
+	if srv.storage, err = storage.Create(&srv.cfg.Storage, srv.keyring, srv.redactor, srv.metrics, srv.log); err != nil {
 		return nil, err
 	}
 
+	// Create config sync
+	if srv.cfg.Sync.Dir != common.EMPTY_STRING {
+		if srv.syncer, err = configsync.Create(&srv.cfg.Sync, srv.storage, srv.log); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create request limiter
+	srv.limits = ratelimit.Create(&srv.cfg.Limits)
+	srv.metrics.RegisterLimiter(srv.limits)
+
 	srv.log.Debug("create application router")
 	srv.router = httprouter.New()
 
+	authenticator := auth.Create(&srv.cfg.Auth)
+
 	srv.log.Debug("register router handlers")
-	handlers.Create(srv.log, srv.storage).Register(srv.router)
+	srv.handlers = handlers.Create(srv.log, srv.storage, authenticator, srv.redactor, srv.limits, srv.metrics)
+	srv.handlers.Register(srv.router)
+
+	if err = srv.createMetricsServer(); err != nil {
+		return nil, err
+	}
+
+	var handler http.Handler = srv.router
+
+	if srv.cfg.Grpc.Enabled {
+		srv.log.Debug("create grpc server")
+		srv.grpcServer = grpcapi.Create(srv.log, srv.storage, authenticator, srv.limits, srv.metrics)
+
+		if srv.cfg.Grpc.Port == common.EMPTY_STRING {
+			handler = srv.grpcHandler(handler)
+		} else if srv.grpcListener, err = net.Listen("tcp", srv.grpcAddr()); err != nil {
+			return nil, err
+		}
+	}
 
 	srv.log.Debug("create http server")
 	srv.server = &http.Server{
-		Handler:      srv.router,
+		Handler:      handler,
 		ReadTimeout:  srv.cfg.Listen.ReadTimeout,
 		WriteTimeout: srv.cfg.Listen.WriteTimeout,
 	}
@@ -73,12 +159,29 @@ func Create(path string) (*ConfigServer, error) {
 func (s *ConfigServer) Run() {
 	s.log.Infof("start listening on %s", s.listenAddr())
 
+	s.storage.StartKeyRotation(s.cfg.Secrets.RotationInterval)
+
+	if s.syncer != nil {
+		s.log.Infof("start config sync from %s", s.cfg.Sync.Dir)
+		s.syncer.Start()
+	}
+
 	stopCh := make(chan os.Signal, 1)
 	signal.Notify(stopCh, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer close(stopCh)
 
 	go s.startServer(stopCh)
 
+	if s.metricsServer != nil {
+		s.log.Infof("start metrics listening on %s", s.metricsAddr())
+		go s.startMetricsServer()
+	}
+
+	if s.grpcListener != nil {
+		s.log.Infof("start grpc listening on %s", s.grpcAddr())
+		go s.startGrpcServer()
+	}
+
 	stop := <-stopCh
 
 	fmt.Println()
@@ -101,16 +204,195 @@ func (s *ConfigServer) startServer(stopCh chan<- os.Signal) {
 
 // stopserver function
 func (s *ConfigServer) stopServer() {
+	// Сообщаем балансировщику, что сервер больше не принимает запросы,
+	// и даем время на вывод сервера из балансировки
+	s.handlers.SetReady(false)
+	if s.cfg.Listen.ShutdownDelay > 0 {
+		s.log.Infof("wait %s before shutdown", s.cfg.Listen.ShutdownDelay)
+		time.Sleep(s.cfg.Listen.ShutdownDelay)
+	}
+
+	if s.syncer != nil {
+		s.syncer.Stop()
+	}
+
+	// Завершаем потоки подписки на новые версии конфигов,
+	// клиенты переподключатся к другим экземплярам сервера
+	s.storage.StopWatch()
+
 	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Listen.ShutdownTimeout)
 	defer cancel()
 
 	if err := s.server.Shutdown(ctx); err != nil {
 		s.log.Fatalln(err)
 	}
+
+	s.stopGrpcServer(ctx)
+
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			s.log.Fatalln(err)
+		}
+	}
+
+	if err := s.tracing(ctx); err != nil {
+		s.log.Errorw("couldn't flush traces", "error", err)
+	}
+
 	s.log.Debug("application shutdown")
 }
 
+// createMetricsServer function
+func (s *ConfigServer) createMetricsServer() error {
+	if !s.cfg.Metrics.Enabled {
+		return nil
+	}
+
+	// Без отдельного порта метрики доступны на основном сервере
+	if s.cfg.Metrics.Port == common.EMPTY_STRING {
+		s.log.Debug("register metrics handler")
+		s.router.Handler(http.MethodGet, s.cfg.Metrics.Path, s.metrics.Handler())
+		return nil
+	}
+
+	s.log.Debug("create metrics http server")
+	mux := http.NewServeMux()
+	mux.Handle(s.cfg.Metrics.Path, s.metrics.Handler())
+
+	s.metricsServer = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  s.cfg.Listen.ReadTimeout,
+		WriteTimeout: s.cfg.Listen.WriteTimeout,
+	}
+
+	var err error
+	s.metricsListener, err = net.Listen("tcp", s.metricsAddr())
+
+	return err
+}
+
+// startMetricsServer function
+func (s *ConfigServer) startMetricsServer() {
+	defer s.metricsListener.Close()
+
+	err := s.metricsServer.Serve(s.metricsListener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.log.Fatalln(err)
+	}
+	s.log.Debug("metrics server stopped gracefully")
+}
+
+// grpcHandler function
+//
+// Разделяет запросы gRPC и HTTP на основном порту. Для работы gRPC
+// без TLS используется HTTP/2 без шифрования (h2c).
+func (s *ConfigServer) grpcHandler(next http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			// Во время остановки новые вызовы отклоняются, клиент
+			// получает статус Unavailable и повторяет вызов
+			if !s.grpcCalls.start() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			defer s.grpcCalls.done()
+
+			s.grpcServer.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+// startGrpcServer function
+func (s *ConfigServer) startGrpcServer() {
+	if err := s.grpcServer.Serve(s.grpcListener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		s.log.Fatalln(err)
+	}
+	s.log.Debug("grpc server stopped gracefully")
+}
+
+// stopGrpcServer function
+func (s *ConfigServer) stopGrpcServer(ctx context.Context) {
+	if s.grpcServer == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+
+	if s.grpcListener == nil {
+		// На основном порту вызовы gRPC обслуживаются через ServeHTTP,
+		// для которого GracefulStop не поддерживается, поэтому
+		// завершения текущих вызовов ожидаем сами
+		go func() {
+			s.grpcCalls.drain()
+			s.grpcServer.Stop()
+			close(stopped)
+		}()
+	} else {
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+}
+
+// grpcCalls struct
+//
+// Текущие вызовы gRPC на основном порту.
+type grpcCalls struct {
+	mu       sync.Mutex
+	draining bool
+	active   sync.WaitGroup
+}
+
+// start function
+func (c *grpcCalls) start() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return false
+	}
+
+	c.active.Add(1)
+	return true
+}
+
+// done function
+func (c *grpcCalls) done() {
+	c.active.Done()
+}
+
+// drain function
+//
+// Запрещает новые вызовы и ожидает завершения текущих.
+func (c *grpcCalls) drain() {
+	c.mu.Lock()
+	c.draining = true
+	c.mu.Unlock()
+
+	c.active.Wait()
+}
+
 // listenAddr function
 func (s *ConfigServer) listenAddr() string {
 	return fmt.Sprintf("%s:%s", s.cfg.Listen.BindIp, s.cfg.Listen.Port)
 }
+
+// metricsAddr function
+func (s *ConfigServer) metricsAddr() string {
+	return fmt.Sprintf("%s:%s", s.cfg.Metrics.BindIp, s.cfg.Metrics.Port)
+}
+
+// grpcAddr function
+func (s *ConfigServer) grpcAddr() string {
+	return fmt.Sprintf("%s:%s", s.cfg.Grpc.BindIp, s.cfg.Grpc.Port)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGrpcCallsDrain(t *testing.T) {
+	var calls grpcCalls
+
+	if !calls.start() {
+		t.Fatal("call is rejected before drain")
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		calls.drain()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("drain completed with an active call")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	calls.done()
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("drain is not completed after the active call")
+	}
+
+	if calls.start() {
+		t.Fatal("call is accepted after drain")
+	}
+}